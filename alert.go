@@ -0,0 +1,79 @@
+package skyndiminni
+
+// capacityAlertHysteresis is how far below the high watermark usage has to drop before the alert fires again
+const capacityAlertHysteresis = 0.05
+
+// WithCapacityAlert calls fn with the usage ratio when usage reaches highWatermark and when it drops back below
+// usage is the larger of the item count over WithMaxItems and the bytes over WithMaxBytes, there is no alert without either
+// to avoid firing on every write near the threshold usage has to drop 0.05 below highWatermark to count as back below
+// fn is called outside the lock after the write that crossed the threshold, alerts are delivered one at a time in order
+func WithCapacityAlert(highWatermark float64, fn func(ratio float64)) Option {
+	return func(c *cache) {
+		c.alertHigh = highWatermark
+		c.alertFn = fn
+	}
+}
+
+// usage returns the used share of the configured limits, the caller must hold the lock
+func (c *cache) usage() float64 {
+	var ratio float64
+	if c.maxItems > 0 {
+		ratio = float64(len(c.items)) / float64(c.maxItems)
+	}
+	if c.maxBytes > 0 {
+		if r := float64(c.bytes) / float64(c.maxBytes); r > ratio {
+			ratio = r
+		}
+	}
+	return ratio
+}
+
+// checkCapacity queues an alert if usage crossed the high watermark, the caller must hold the write lock
+func (c *cache) checkCapacity() {
+	if c.alertFn == nil || (c.maxItems <= 0 && c.maxBytes <= 0) {
+		return
+	}
+	ratio := c.usage()
+	switch {
+	case !c.alertAbove && ratio >= c.alertHigh:
+		c.alertAbove = true
+	case c.alertAbove && ratio < c.alertHigh-capacityAlertHysteresis:
+		c.alertAbove = false
+	default:
+		return
+	}
+	c.alertMut.Lock()
+	c.alerts = append(c.alerts, ratio)
+	c.alertMut.Unlock()
+}
+
+// capacityAlerts calls the alert callback for every queued alert in order, the caller must not hold the lock
+// one goroutine delivers at a time under alertSendMut, a call made while another is delivering, such as a write
+// from inside fn, returns at once and leaves its alerts to that goroutine
+func (c *cache) capacityAlerts() {
+	if c.alertFn == nil {
+		return
+	}
+	for c.alertSendMut.TryLock() {
+		for {
+			c.alertMut.Lock()
+			alerts := c.alerts
+			c.alerts = nil
+			c.alertMut.Unlock()
+			if len(alerts) == 0 {
+				break
+			}
+			for _, ratio := range alerts {
+				c.safeCall("capacity alert callback", func() { c.alertFn(ratio) })
+			}
+		}
+		c.alertSendMut.Unlock()
+		// an alert queued after the last drain but before the unlock was left by a goroutine that failed TryLock
+		c.alertMut.Lock()
+		queued := len(c.alerts) > 0
+		c.alertMut.Unlock()
+		if !queued {
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCapacityAlert(t *testing.T) {
+	var mut sync.Mutex
+	var ratios []float64
+	c := newTestCache(t, NoExpiration, WithMaxItems(100), WithCapacityAlert(0.8, func(ratio float64) {
+		mut.Lock()
+		ratios = append(ratios, ratio)
+		mut.Unlock()
+	}))
+	alerts := func() string {
+		mut.Lock()
+		defer mut.Unlock()
+		return fmt.Sprint(ratios)
+	}
+	set := func(i int) {
+		if _, err := c.SetWithTTL(fmt.Sprint(i), i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 79; i++ {
+		set(i)
+	}
+	if got := alerts(); got != "[]" {
+		t.Fatalf("alerts below the watermark = %s", got)
+	}
+	set(79)
+	set(80)
+	if got := alerts(); got != "[0.8]" {
+		t.Fatalf("alerts after crossing up = %s, want [0.8]", got)
+	}
+
+	// moving around just under the watermark does not fire again
+	for i := 80; i >= 77; i-- {
+		if err := c.Delete(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 77; i <= 80; i++ {
+		set(i)
+	}
+	if got := alerts(); got != "[0.8]" {
+		t.Fatalf("alerts near the watermark = %s, want no new ones", got)
+	}
+
+	for i := 80; i >= 74; i-- {
+		if err := c.Delete(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := alerts(); got != "[0.8 0.74]" {
+		t.Fatalf("alerts after dropping back = %s, want [0.8 0.74]", got)
+	}
+}
+
+func TestCapacityAlertOrder(t *testing.T) {
+	var mut sync.Mutex
+	var ratios []float64
+	var c *Cache
+	c = newTestCache(t, NoExpiration, WithMaxItems(10), WithCapacityAlert(0.5, func(ratio float64) {
+		// alerts going up are slow to record so a later alert going down would overtake them without ordering
+		if ratio >= 0.5 {
+			time.Sleep(100 * time.Microsecond)
+		}
+		mut.Lock()
+		ratios = append(ratios, ratio)
+		mut.Unlock()
+		// a write from inside the callback must not block on delivery
+		if _, err := c.Update("base0", ratio, 0, false); err != nil {
+			t.Error(err)
+		}
+	}))
+	for i := 0; i < 4; i++ {
+		if _, err := c.SetWithTTL(fmt.Sprint("base", i), i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// setters push usage across the watermark and deleters bring it back, so the alerts for one crossing and the
+	// next are sent from different goroutines, each alert has to flip the state of the one before
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				_, _ = c.SetWithTTL("k", i, time.Hour)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				_ = c.Delete("k")
+			}
+		}()
+	}
+	waitTimeout(t, &wg)
+
+	mut.Lock()
+	defer mut.Unlock()
+	if len(ratios) == 0 {
+		t.Fatal("no alerts")
+	}
+	for i, r := range ratios {
+		if above := r >= 0.5; above != (i%2 == 0) {
+			t.Fatalf("alert %d has ratio %v out of order in %v", i, r, ratios)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package skyndiminni
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker stops calls to the backing store after repeated failures
+type breaker struct {
+	mut       sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	// tripped is set once the breaker opens and cleared by the next success
+	// a failure while tripped opens the breaker again straight away
+	tripped bool
+}
+
+// WithStoreBreaker stops calling the backing store for cooldown after failures consecutive failures
+// while it is open misses are served directly, the first call after the cooldown reopens it if it fails
+// the backing store is the fetch set by WithBulkHydration
+func WithStoreBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *cache) {
+		c.breaker = &breaker{
+			threshold: failures,
+			cooldown:  cooldown,
+		}
+	}
+}
+
+// allow reports if the store can be called
+func (b *breaker) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return !now.Before(b.openUntil)
+}
+
+// record counts the result of a store call and opens the breaker once there are enough failures
+func (b *breaker) record(err error, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.tripped = false
+		return
+	}
+	b.failures++
+	if b.tripped || b.failures >= b.threshold {
+		b.failures = 0
+		b.tripped = true
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
@@ -0,0 +1,72 @@
+package skyndiminni
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoreBreaker(t *testing.T) {
+	var calls, fail int32 = 0, 1
+	c := newTestCache(t, time.Hour, WithStoreBreaker(3, 100*time.Millisecond), WithBulkHydration(func(keys []string) (map[string]interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			return nil, errors.New("store down")
+		}
+		return map[string]interface{}{"k": "value"}, nil
+	}))
+
+	for i := 0; i < 3; i++ {
+		c.Get("k")
+	}
+	if !c.Stats().StoreBreakerOpen {
+		t.Fatal("breaker did not open after 3 failures")
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := c.Get("k"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("Get while open err = %v, want a plain miss", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("store called %d times, want no calls while open", n)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if c.Stats().StoreBreakerOpen {
+		t.Fatal("breaker still open after the cooldown")
+	}
+	atomic.StoreInt32(&fail, 0)
+	if item, err := c.Get("k"); err != nil || item.Value != "value" {
+		t.Fatalf("Get after the cooldown = %v, %v", item, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("store called %d times, want one call after the cooldown", n)
+	}
+}
+
+func TestStoreBreakerReopensOnFailedProbe(t *testing.T) {
+	b := &breaker{threshold: 2, cooldown: time.Minute}
+	now := time.Now()
+	b.record(errors.New("fail"), now)
+	if !b.allow(now) {
+		t.Fatal("opened after one failure")
+	}
+	b.record(errors.New("fail"), now)
+	if b.allow(now) {
+		t.Fatal("not open after two failures")
+	}
+	later := now.Add(time.Minute)
+	if !b.allow(later) {
+		t.Fatal("still open after the cooldown")
+	}
+	b.record(errors.New("fail"), later)
+	if b.allow(later) {
+		t.Fatal("a failed probe did not open the breaker again")
+	}
+	b.record(nil, later.Add(time.Minute))
+	b.record(errors.New("fail"), later.Add(time.Minute))
+	if !b.allow(later.Add(time.Minute)) {
+		t.Fatal("a success did not reset the failures")
+	}
+}
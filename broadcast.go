@@ -0,0 +1,62 @@
+package skyndiminni
+
+// Broadcaster carries key invalidations between cache instances, e.g. over a pub/sub bus
+// Subscribe must not deliver keys published by the same cache back to it
+// otherwise a cache would evict its own writes
+type Broadcaster interface {
+	Publish(key string) error
+	Subscribe() (<-chan string, error)
+}
+
+// WithBroadcaster publishes every key written by Set, Add, Update, SetWithCallback, Rename and Delete to b
+// and evicts keys received from b locally without publishing them again
+// a failed Publish is returned by the method after the local change has been applied
+// changes made through WithLock are not published
+func WithBroadcaster(b Broadcaster) Option {
+	return func(c *cache) {
+		c.broadcaster = b
+	}
+}
+
+// subscribe starts the goroutine evicting keys invalidated by other instances
+func (c *cache) subscribe() error {
+	ch, err := c.broadcaster.Subscribe()
+	if err != nil {
+		return err
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			select {
+			case key, ok := <-ch:
+				if !ok {
+					return
+				}
+				c.invalidate(key)
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// invalidate evicts a key received from the broadcaster
+func (c *cache) invalidate(key string) {
+	c.mut.Lock()
+	item := c.remove(key, EvictionInvalidated)
+	c.mut.Unlock()
+	if item != nil {
+		c.evicted([]evictedItem{{key, item}})
+	}
+}
+
+// publish sends the key to the broadcaster if one is configured
+func (c *cache) publish(key string) error {
+	if c.broadcaster == nil {
+		return nil
+	}
+	return c.broadcaster.Publish(key)
+}
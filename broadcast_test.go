@@ -0,0 +1,95 @@
+package skyndiminni
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBus connects its members in memory, a key published by one member is delivered to every other member
+type fakeBus struct {
+	mut       sync.Mutex
+	members   []*busMember
+	published int
+}
+
+type busMember struct {
+	bus *fakeBus
+	ch  chan string
+}
+
+func (b *fakeBus) join() *busMember {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	m := &busMember{bus: b, ch: make(chan string, 16)}
+	b.members = append(b.members, m)
+	return m
+}
+
+func (m *busMember) Publish(key string) error {
+	m.bus.mut.Lock()
+	defer m.bus.mut.Unlock()
+	m.bus.published++
+	for _, other := range m.bus.members {
+		if other != m {
+			other.ch <- key
+		}
+	}
+	return nil
+}
+
+func (m *busMember) Subscribe() (<-chan string, error) {
+	return m.ch, nil
+}
+
+func (b *fakeBus) publishes() int {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.published
+}
+
+// eventually fails the test if cond does not become true within a second
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroadcasterInvalidatesOtherInstances(t *testing.T) {
+	bus := &fakeBus{}
+	a := newTestCache(t, NoExpiration, WithBroadcaster(bus.join()))
+	b := newTestCache(t, NoExpiration, WithBroadcaster(bus.join()))
+	// writes made through WithLock are not published so both caches can hold the key
+	for _, c := range []*Cache{a, b} {
+		c.WithLock(func(tx *Tx) {
+			if _, err := tx.Set("k", 1, inAnHour()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if err := a.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, err := b.Get("k")
+		return err != nil
+	})
+
+	if _, err := b.Set("other", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get("other"); err != nil {
+		t.Fatal("a cache evicted its own write")
+	}
+	// received invalidations are not published again
+	time.Sleep(10 * time.Millisecond)
+	if n := bus.publishes(); n != 2 {
+		t.Fatalf("bus saw %d publishes, want 2", n)
+	}
+}
@@ -0,0 +1,79 @@
+package skyndiminni
+
+import "time"
+
+// DefaultBucketSize is the bucket size used by SetInBucket when WithBucketSize is not set
+const DefaultBucketSize = time.Hour
+
+// WithBucketSize sets the length of the time buckets used by SetInBucket and ExpireBucket
+func WithBucketSize(d time.Duration) Option {
+	return func(c *cache) {
+		c.bucketSize = d
+	}
+}
+
+// SetInBucket works like SetWithTTL with the default expiration and files the key under the time bucket containing bucket
+func (c *Cache) SetInBucket(bucket time.Time, key string, value interface{}) (*Item, error) {
+	expirationTime, err := c.expiration(0)
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+		bucket:     c.bucketOf(bucket),
+		inBucket:   true,
+	}
+	if c.setOverwrite {
+		return c.set(key, item)
+	}
+	return c.add(key, item)
+}
+
+// ExpireBucket removes every key in the time bucket containing bucket and returns how many were removed
+func (c *Cache) ExpireBucket(bucket time.Time) int {
+	c.mut.Lock()
+	var removed []evictedItem
+	for k := range c.buckets[c.bucketOf(bucket)] {
+		removed = append(removed, evictedItem{k, c.remove(k, EvictionExpired)})
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+
+	for _, e := range removed {
+		if err := c.publish(e.key); err != nil {
+			c.reportError(err)
+		}
+	}
+	return len(removed)
+}
+
+// bucketOf quantizes t to the start of its bucket
+func (c *cache) bucketOf(t time.Time) int64 {
+	return t.Truncate(c.bucketSize).Unix()
+}
+
+// indexBucket adds the key to its item's bucket, the caller must hold the write lock
+func (c *cache) indexBucket(key string, item *Item) {
+	if !item.inBucket {
+		return
+	}
+	keys := c.buckets[item.bucket]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		c.buckets[item.bucket] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// unindexBucket removes the key from its item's bucket, the caller must hold the write lock
+func (c *cache) unindexBucket(key string, item *Item) {
+	if !item.inBucket {
+		return
+	}
+	keys := c.buckets[item.bucket]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(c.buckets, item.bucket)
+	}
+}
@@ -0,0 +1,50 @@
+package skyndiminni
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpireBucket(t *testing.T) {
+	c := newTestCache(t, time.Hour, WithBucketSize(time.Minute))
+	first := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := c.SetInBucket(first.Add(10*time.Second), k, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.SetInBucket(second, "d", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	// any time inside the bucket selects it
+	if n := c.ExpireBucket(first.Add(59 * time.Second)); n != 2 {
+		t.Fatalf("ExpireBucket removed %d, want 2", n)
+	}
+	got := c.HasMany([]string{"a", "b", "d"})
+	if got["a"] || got["b"] || !got["d"] {
+		t.Fatalf("present after expiring the first bucket = %v", got)
+	}
+	if n := c.ExpireBucket(first); n != 0 {
+		t.Fatalf("expiring the bucket again removed %d", n)
+	}
+
+	// replacing a key with a plain Set takes it out of its bucket
+	c.WithLock(func(tx *Tx) {
+		if _, err := tx.Set("d", 2, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if n := c.ExpireBucket(second); n != 0 {
+		t.Fatalf("ExpireBucket removed %d replaced keys", n)
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if len(c.buckets) != 0 {
+		t.Fatalf("buckets left in the index: %v", c.buckets)
+	}
+}
@@ -0,0 +1,236 @@
+package skyndiminni
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrCacheFull is returned when a new key does not fit and WithRejectOnFull(true) is set or every item is pinned
+	ErrCacheFull = errors.New("cache is full")
+	// ErrValueTooLarge is returned when a value is bigger than the size set by WithMaxValueSize
+	ErrValueTooLarge = errors.New("value is too large")
+	// ErrKeyNotFound is returned when a key does not exist
+	ErrKeyNotFound = errors.New("key does not exist")
+	// ErrKeyExpired is returned by Get when the key was stored but has expired
+	ErrKeyExpired = errors.New("key has expired")
+)
+
+// WithMaxItems bounds the number of items in the cache
+// storing a new key in a full cache evicts expired items first and then the oldest item
+func WithMaxItems(n int) Option {
+	return func(c *cache) {
+		c.maxItems = n
+	}
+}
+
+// WithMaxBytes bounds the total size of the values in the cache as measured by the sizer
+// storing a value that would go over evicts like WithMaxItems, a value bigger than the whole budget returns ErrValueTooLarge
+// DefaultSizer is used unless WithSizer is set
+func WithMaxBytes(bytes int64) Option {
+	return func(c *cache) {
+		c.maxBytes = bytes
+	}
+}
+
+// WithEvictionBatch makes a write that needs room evict up to n items at once instead of one at a time
+// it saves scanning for a victim per eviction when the cache is far over its limits, for example after Resize
+// the cache can then drop up to n-1 items below its limit, the default of 1 evicts only what is needed
+func WithEvictionBatch(n int) Option {
+	return func(c *cache) {
+		c.evictBatch = n
+	}
+}
+
+// Evict removes about fraction of the stored items, expired items first and then the oldest, and returns how many it removed
+// it is meant for memory pressure handlers, fraction is limited to between 0 and 1, pinned items are skipped
+func (c *Cache) Evict(fraction float64) int {
+	if fraction <= 0 {
+		return 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	c.mut.Lock()
+	n := int(math.Round(fraction * float64(len(c.items))))
+	var removed []evictedItem
+	if n > 0 {
+		for _, v := range c.victims(time.Now(), n) {
+			reason := EvictionCapacity
+			if v.expired {
+				reason = EvictionExpired
+			}
+			removed = append(removed, evictedItem{v.key, c.remove(v.key, reason)})
+		}
+	}
+	c.mut.Unlock()
+	// like capacity evictions these are local so they are not published
+	c.evicted(removed)
+	return len(removed)
+}
+
+// Resize changes the max number of items set by WithMaxItems, 0 or less is no limit
+// items over a lowered limit are evicted by the next writes that need room rather than straight away
+func (c *Cache) Resize(maxItems int) {
+	c.mut.Lock()
+	c.maxItems = maxItems
+	c.mut.Unlock()
+}
+
+// WithRejectOnFull makes storing a new key in a full cache return ErrCacheFull instead of evicting live items
+// expired items are still removed to make room
+func WithRejectOnFull(reject bool) Option {
+	return func(c *cache) {
+		c.rejectOnFull = reject
+	}
+}
+
+// WithSizer sets the function used to measure the size of values in bytes
+func WithSizer(sizer func(value interface{}) int64) Option {
+	return func(c *cache) {
+		c.sizer = sizer
+	}
+}
+
+// WithMaxValueSize rejects storing any value the sizer measures bigger than bytes with ErrValueTooLarge
+// DefaultSizer is used unless WithSizer is set
+func WithMaxValueSize(bytes int64) Option {
+	return func(c *cache) {
+		c.maxValueSize = bytes
+	}
+}
+
+// measure records the size of the item's value, compresses or encrypts it and checks it against the size limits
+// sizers can be slow so this is called before taking the write lock where possible
+func (c *cache) measure(item *Item) error {
+	// sizes given by the caller, like the cost of SetWithCost, are kept even if the value is compressed or encrypted
+	measured := !item.sized && c.sizer != nil
+	if !item.sized {
+		if c.sizer != nil {
+			if err := c.safeCall("sizer", func() { item.size = c.sizer(item.Value) }); err != nil {
+				return err
+			}
+		}
+		item.rawSize = item.size
+		item.sized = true
+	}
+	if _, ok := item.Value.(storedValue); !ok {
+		var n int64
+		if c.aead != nil {
+			var err error
+			if n, err = c.encrypt(item); err != nil {
+				return err
+			}
+		} else if c.compressMin > 0 {
+			n = c.compress(item)
+		}
+		if n > 0 && measured {
+			item.size = n + sliceHeader
+		}
+	}
+	if (c.maxValueSize > 0 && item.size > c.maxValueSize) || (c.maxBytes > 0 && item.size > c.maxBytes) {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// makeRoom evicts items until the item fits under key, the caller must hold the write lock
+// returns the evicted items and ErrCacheFull if only live items could be evicted and rejecting is on
+// or if every item left is pinned
+func (c *cache) makeRoom(key string, item *Item, now time.Time) ([]evictedItem, error) {
+	var removed []evictedItem
+	for c.full(key, item) {
+		picked := c.victims(now, c.evictBatch)
+		if len(picked) == 0 {
+			return removed, ErrCacheFull
+		}
+		for _, v := range picked {
+			if !v.expired && c.rejectOnFull {
+				return removed, ErrCacheFull
+			}
+			reason := EvictionCapacity
+			if v.expired {
+				reason = EvictionExpired
+			}
+			removed = append(removed, evictedItem{v.key, c.remove(v.key, reason)})
+		}
+	}
+	return removed, nil
+}
+
+// full reports if storing item under key would go over the max items or max bytes
+func (c *cache) full(key string, item *Item) bool {
+	if len(c.items) == 0 {
+		return false
+	}
+	existing := c.items[key]
+	if c.maxItems > 0 && existing == nil && len(c.items) >= c.maxItems {
+		return true
+	}
+	if c.maxBytes > 0 {
+		bytes := c.bytes + item.size
+		if existing != nil {
+			bytes -= existing.size
+		}
+		return bytes > c.maxBytes
+	}
+	return false
+}
+
+// victimKey is a key picked for eviction and if it had expired
+type victimKey struct {
+	key     string
+	expired bool
+}
+
+// victims picks up to n keys to evict, expired items first and then the oldest live item that is not pinned
+// the caller must hold the lock, returns none if every item is live and pinned
+func (c *cache) victims(now time.Time, n int) []victimKey {
+	if n <= 1 {
+		key, expired, ok := c.victim(now)
+		if !ok {
+			return nil
+		}
+		return []victimKey{{key, expired}}
+	}
+
+	picked := make([]victimKey, 0, n)
+	var alive []evictedItem
+	for k, item := range c.items {
+		if item.IsExpired(now) {
+			picked = append(picked, victimKey{k, true})
+			if len(picked) == n {
+				return picked
+			}
+			continue
+		}
+		if !item.pinned {
+			alive = append(alive, evictedItem{k, item})
+		}
+	}
+	sort.Slice(alive, func(i, j int) bool { return alive[i].item.creationTime < alive[j].item.creationTime })
+	for _, e := range alive {
+		if len(picked) == n {
+			break
+		}
+		picked = append(picked, victimKey{e.key, false})
+	}
+	return picked
+}
+
+// victim picks the key to evict, an expired item if there is one otherwise the oldest item that is not pinned
+// the caller must hold the lock, ok is false if there is nothing to evict
+func (c *cache) victim(now time.Time) (key string, expired, ok bool) {
+	var oldest *Item
+	for k, item := range c.items {
+		if item.IsExpired(now) {
+			return k, true, true
+		}
+		if !item.pinned && (oldest == nil || item.creationTime < oldest.creationTime) {
+			key, oldest = k, item
+		}
+	}
+	return key, false, oldest != nil
+}
@@ -0,0 +1,180 @@
+package skyndiminni
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFullCacheEvictsOrRejects(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reject=%v", reject), func(t *testing.T) {
+			c := newTestCache(t, NoExpiration, WithMaxItems(2), WithRejectOnFull(reject))
+			for _, k := range []string{"a", "b"} {
+				if _, err := c.Set(k, k, inAnHour()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			backdate(c, "a", 10*time.Second)
+
+			_, err := c.Set("c", "c", inAnHour())
+			if reject {
+				if !errors.Is(err, ErrCacheFull) {
+					t.Fatalf("Set err = %v, want ErrCacheFull", err)
+				}
+				if !c.HasMany([]string{"a"})["a"] || !c.HasMany([]string{"b"})["b"] {
+					t.Fatal("a rejected Set removed existing entries")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set err = %v", err)
+			}
+			got := c.HasMany([]string{"a", "b", "c"})
+			if got["a"] || !got["b"] || !got["c"] {
+				t.Fatalf("after eviction present = %v, want the oldest evicted", got)
+			}
+		})
+	}
+}
+
+func TestRejectOnFullStillRemovesExpired(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxItems(1), WithRejectOnFull(true))
+	if _, err := c.Set("old", 1, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("new", 1, inAnHour()); err != nil {
+		t.Fatalf("Set over an expired item err = %v", err)
+	}
+}
+
+func TestMaxBytesRejectOnFull(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxBytes(100), WithRejectOnFull(true), WithSizer(func(v interface{}) int64 {
+		return int64(len(v.(string)))
+	}))
+	if _, err := c.Set("a", string(make([]byte, 60)), inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("b", string(make([]byte, 60)), inAnHour()); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("Set over max bytes err = %v", err)
+	}
+	if _, err := c.Get("a"); err != nil {
+		t.Fatal("existing entry was removed")
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxValueSize(100))
+	if _, err := c.Set("small", string(make([]byte, 50)), inAnHour()); err != nil {
+		t.Fatalf("under size Set err = %v", err)
+	}
+	if _, err := c.Set("big", string(make([]byte, 500)), inAnHour()); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("over size Set err = %v", err)
+	}
+	if _, err := c.Get("big"); err == nil {
+		t.Fatal("over size value was stored")
+	}
+}
+
+func TestEvictionBatchAfterResize(t *testing.T) {
+	const before, after, batch = 1000, 100, 16
+	c := newTestCache(t, NoExpiration, WithMaxItems(before), WithEvictionBatch(batch))
+	exp := inAnHour()
+	for i := 0; i < before; i++ {
+		if _, err := c.Set(fmt.Sprint("old", i), i, exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.Resize(after)
+	for i := 0; i < 200; i++ {
+		if _, err := c.Set(fmt.Sprint("new", i), i, exp); err != nil {
+			t.Fatal(err)
+		}
+		if n := c.Len(); n > after || n < after-batch {
+			t.Fatalf("after set %d the cache holds %d items, want between %d and %d", i, n, after-batch, after)
+		}
+	}
+}
+
+func TestEvictionBatchDefault(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxItems(50))
+	exp := inAnHour()
+	for i := 0; i < 50; i++ {
+		if _, err := c.Set(fmt.Sprint("old", i), i, exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Resize(10)
+	for i := 0; i < 20; i++ {
+		if _, err := c.Set(fmt.Sprint("new", i), i, exp); err != nil {
+			t.Fatal(err)
+		}
+		if n := c.Len(); n != 10 {
+			t.Fatalf("after set %d the cache holds %d items, want exactly 10", i, n)
+		}
+	}
+}
+
+// BenchmarkResizeConvergence measures the first Set after shrinking a full cache to a tenth of its size
+func BenchmarkResizeConvergence(b *testing.B) {
+	const before, after = 5000, 500
+	keys := make([]string, before)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	for _, batch := range []int{1, 64, 1024} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			exp := inAnHour()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				c := newTestCache(b, NoExpiration, WithMaxItems(before), WithEvictionBatch(batch))
+				for _, k := range keys {
+					c.Set(k, 1, exp)
+				}
+				c.Resize(after)
+				b.StartTimer()
+				c.Set("new", 1, exp)
+			}
+		})
+	}
+}
+
+func TestEvictFraction(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	for i := 0; i < 10; i++ {
+		if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 0 and 1 have expired, 2, 3 and 4 are the oldest live items but 2 is pinned
+	expire(c, "0")
+	expire(c, "1")
+	for i := 2; i <= 5; i++ {
+		backdate(c, fmt.Sprint(i), time.Duration(10-i)*time.Minute)
+	}
+	if err := c.Pin("2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := c.Evict(0.5); n != 5 {
+		t.Fatalf("Evict(0.5) removed %d items, want 5", n)
+	}
+	var left []string
+	for i := 0; i < 10; i++ {
+		if _, err := c.Get(fmt.Sprint(i)); err == nil {
+			left = append(left, fmt.Sprint(i))
+		}
+	}
+	if got := fmt.Sprint(left); got != "[2 6 7 8 9]" {
+		t.Fatalf("left %s, want the expired and oldest unpinned items evicted", got)
+	}
+
+	if n := c.Evict(0); n != 0 {
+		t.Fatalf("Evict(0) removed %d items", n)
+	}
+	if n := c.Evict(2); n != 4 || c.Len() != 1 {
+		t.Fatalf("Evict(2) removed %d items leaving %d, want every unpinned item gone", n, c.Len())
+	}
+}
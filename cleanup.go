@@ -0,0 +1,40 @@
+package skyndiminni
+
+import "time"
+
+const (
+	// cleanupBusy is the share of expired items in a sweep above which the cleanup interval is halved
+	cleanupBusy = 0.25
+	// cleanupIdle is the share of expired items in a sweep below which the cleanup interval is doubled
+	cleanupIdle = 0.01
+)
+
+// WithCleanupBounds makes the cleanup interval adapt to how many items each sweep removes, staying between min and max
+// it is halved after a sweep where more than a quarter of the items had expired and doubled after one where under 1% had
+// the interval starts from the usual cleanup interval and is kept within the bounds from the first sweep on
+func WithCleanupBounds(min, max time.Duration) Option {
+	return func(c *cache) {
+		c.cleanupMin = min
+		c.cleanupMax = max
+	}
+}
+
+// nextCleanup returns the interval to wait before the next sweep given the share of items the last one removed
+func (c *cache) nextCleanup(interval time.Duration, expired float64) time.Duration {
+	if c.cleanupMin <= 0 || c.cleanupMax < c.cleanupMin {
+		return interval
+	}
+	switch {
+	case expired > cleanupBusy:
+		interval /= 2
+	case expired < cleanupIdle:
+		interval *= 2
+	}
+	if interval < c.cleanupMin {
+		interval = c.cleanupMin
+	}
+	if interval > c.cleanupMax {
+		interval = c.cleanupMax
+	}
+	return interval
+}
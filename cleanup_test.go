@@ -0,0 +1,68 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNextCleanupAdapts(t *testing.T) {
+	c := &cache{cleanupMin: time.Second, cleanupMax: time.Minute}
+
+	interval := 8 * time.Second
+	var shrinking []time.Duration
+	for i := 0; i < 5; i++ {
+		interval = c.nextCleanup(interval, 0.5)
+		shrinking = append(shrinking, interval)
+	}
+	if got := fmt.Sprint(shrinking); got != "[4s 2s 1s 1s 1s]" {
+		t.Fatalf("heavy expiry intervals = %s, want halving down to the minimum", got)
+	}
+
+	var growing []time.Duration
+	for i := 0; i < 8; i++ {
+		interval = c.nextCleanup(interval, 0)
+		growing = append(growing, interval)
+	}
+	if got := fmt.Sprint(growing); got != "[2s 4s 8s 16s 32s 1m0s 1m0s 1m0s]" {
+		t.Fatalf("idle intervals = %s, want doubling up to the maximum", got)
+	}
+
+	if next := c.nextCleanup(10*time.Second, 0.1); next != 10*time.Second {
+		t.Fatalf("moderate expiry changed the interval to %v", next)
+	}
+	// an interval outside the bounds is pulled into them on the first sweep
+	if next := c.nextCleanup(time.Hour, 0.1); next != time.Minute {
+		t.Fatalf("interval above the bounds became %v", next)
+	}
+}
+
+func TestNextCleanupWithoutBounds(t *testing.T) {
+	for _, c := range []*cache{{}, {cleanupMin: time.Minute, cleanupMax: time.Second}} {
+		if next := c.nextCleanup(time.Second, 0.9); next != time.Second {
+			t.Fatalf("bounds %v-%v changed the interval to %v", c.cleanupMin, c.cleanupMax, next)
+		}
+	}
+}
+
+func TestCleanupSweepRatio(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithCleanupBounds(time.Second, time.Minute))
+	for i := 0; i < 10; i++ {
+		if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		expire(c, fmt.Sprint(i))
+	}
+	ratio := c.initExpiration()
+	if ratio != 0.5 {
+		t.Fatalf("sweep removed a share of %v, want 0.5", ratio)
+	}
+	if next := c.nextCleanup(10*time.Second, ratio); next != 5*time.Second {
+		t.Fatalf("interval after a busy sweep = %v", next)
+	}
+	if next := c.nextCleanup(10*time.Second, c.initExpiration()); next != 20*time.Second {
+		t.Fatalf("interval after an idle sweep = %v", next)
+	}
+}
@@ -0,0 +1,74 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// compressedValue is a string or byte slice value stored gzip compressed
+type compressedValue struct {
+	data     []byte
+	isString bool
+}
+
+// WithCompression gzip compresses string and byte slice values longer than minBytes when they are stored
+// values are decompressed into a copy of the item whenever it is returned so callers never see the compressed form
+// the max bytes budget counts the compressed size, Stats reports both sizes
+// DefaultSizer is used unless WithSizer is set
+// compression is skipped when it does not make the value smaller, other value types and compressed values are not interned
+func WithCompression(minBytes int) Option {
+	return func(c *cache) {
+		c.compressMin = minBytes
+	}
+}
+
+// compress replaces a large string or byte slice value with its compressed form
+// returns the compressed length, 0 if the value was left as is
+func (c *cache) compress(item *Item) int64 {
+	var raw []byte
+	isString := false
+	switch v := item.Value.(type) {
+	case string:
+		raw, isString = []byte(v), true
+	case []byte:
+		raw = v
+	default:
+		return 0
+	}
+	if len(raw) <= c.compressMin {
+		return 0
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	// writes to a bytes.Buffer do not fail
+	w.Write(raw)
+	w.Close()
+	if buf.Len() >= len(raw) {
+		return 0
+	}
+	item.Value = &compressedValue{data: buf.Bytes(), isString: isString}
+	return int64(buf.Len())
+}
+
+// value decompresses the stored value back to its original type, nil if the data is corrupt
+func (cv *compressedValue) value() interface{} {
+	r, err := gzip.NewReader(bytes.NewReader(cv.data))
+	if err != nil {
+		return nil
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	if cv.isString {
+		return string(raw)
+	}
+	return raw
+}
+
+// storedSize returns the length of the compressed data
+func (cv *compressedValue) storedSize() int64 {
+	return int64(len(cv.data))
+}
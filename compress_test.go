@@ -0,0 +1,45 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithCompression(64))
+	long := strings.Repeat("compressible ", 1000)
+	raw := bytes.Repeat([]byte{1}, 1000)
+	if _, err := c.Set("s", long, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("b", raw, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("short", "tiny", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mut.RLock()
+	_, sCompressed := c.items["s"].Value.(*compressedValue)
+	_, shortCompressed := c.items["short"].Value.(*compressedValue)
+	c.mut.RUnlock()
+	if !sCompressed || shortCompressed {
+		t.Fatalf("compressed long = %v, short = %v", sCompressed, shortCompressed)
+	}
+
+	if item, err := c.Get("s"); err != nil || item.Value != long {
+		t.Fatalf("Get(s) did not return the original string, err = %v", err)
+	}
+	if item, err := c.Get("b"); err != nil || !bytes.Equal(item.Value.([]byte), raw) {
+		t.Fatalf("Get(b) = %v, %v", item, err)
+	}
+
+	stats := c.Stats()
+	if stats.UncompressedBytes < int64(len(long)+len(raw)) {
+		t.Fatalf("UncompressedBytes = %d", stats.UncompressedBytes)
+	}
+	if stats.Bytes == 0 || stats.Bytes >= stats.UncompressedBytes {
+		t.Fatalf("Bytes = %d, UncompressedBytes = %d", stats.Bytes, stats.UncompressedBytes)
+	}
+}
@@ -0,0 +1,30 @@
+package skyndiminni
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTooSoon is returned when a key is overwritten within the WithSetCooldown duration of being set
+var ErrTooSoon = errors.New("key was set too recently")
+
+// WithSetCooldown rejects any write overwriting a non expired key set less than d ago with ErrTooSoon
+// in place changes that keep the item's creation time, like IncrementOrCreate and ExtendTTL, are not limited
+// creation times are kept in seconds, so the cooldown is only accurate to about a second
+func WithSetCooldown(d time.Duration) Option {
+	return func(c *cache) {
+		c.setCooldown = d
+	}
+}
+
+// tooSoon reports if key holds a non expired item still inside the set cooldown, the caller must hold the lock
+func (c *cache) tooSoon(key string, now time.Time) bool {
+	if c.setCooldown <= 0 {
+		return false
+	}
+	old := c.items[key]
+	if old == nil || old.IsExpired(now) {
+		return false
+	}
+	return now.Sub(time.Unix(old.creationTime, 0)) < c.setCooldown
+}
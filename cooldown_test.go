@@ -0,0 +1,38 @@
+package skyndiminni
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetCooldown(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetCooldown(time.Minute), WithSetOverwrite(true))
+	if _, err := c.SetWithTTL("k", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithTTL("k", 2, time.Hour); !errors.Is(err, ErrTooSoon) {
+		t.Fatalf("immediate re-set err = %v, want ErrTooSoon", err)
+	}
+	if item, _ := c.Get("k"); item.Value != 1 {
+		t.Fatalf("rejected set overwrote the value with %v", item.Value)
+	}
+	// other keys are not limited
+	if _, err := c.SetWithTTL("other", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	backdate(c, "k", 2*time.Minute)
+	if _, err := c.SetWithTTL("k", 3, time.Hour); err != nil {
+		t.Fatalf("re-set after the cooldown: %v", err)
+	}
+	if item, _ := c.Get("k"); item.Value != 3 {
+		t.Fatalf("value after the cooldown = %v", item.Value)
+	}
+
+	// an expired key can be set again right away
+	expire(c, "other")
+	if _, err := c.SetWithTTL("other", 2, time.Hour); err != nil {
+		t.Fatalf("re-set of an expired key: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package skyndiminni
+
+import (
+	"time"
+	"unsafe"
+)
+
+// CopyTo copies the non expired keys in keys into dst with their expiration, creation time and onExpire callback
+// existing live keys in dst are only replaced if overwrite is set, returns the number of keys copied
+// c's read lock and dst's write lock are held together so the copy is atomic, dst's sizer runs under both
+func (c *Cache) CopyTo(dst *Cache, keys []string, overwrite bool) int {
+	if dst.cache == c.cache {
+		return 0
+	}
+
+	unlock := c.lockWith(dst)
+	now := time.Now()
+	var removed []evictedItem
+	copied := make([]string, 0, len(keys))
+	for _, k := range keys {
+		item := c.items[k]
+		if item == nil || item.IsExpired(now) {
+			continue
+		}
+		if existing := dst.items[k]; existing != nil {
+			if !existing.IsExpired(now) {
+				if !overwrite {
+					continue
+				}
+			} else {
+				removed = append(removed, evictedItem{k, dst.remove(k, EvictionExpired)})
+			}
+		}
+		// the item is measured again as dst may have its own sizer and compression
+		next := &Item{
+			Value:        item.view().Value,
+			Expiration:   item.Expiration,
+			creationTime: item.creationTime,
+			onExpire:     item.onExpire,
+		}
+		if err := dst.measure(next); err != nil {
+			continue
+		}
+		evicted, err := dst.store(k, next, now)
+		removed = append(removed, evicted...)
+		if err == nil {
+			copied = append(copied, k)
+		}
+	}
+	unlock()
+	dst.evicted(removed)
+
+	for _, k := range copied {
+		if err := dst.publish(k); err != nil {
+			dst.reportError(err)
+		}
+	}
+	return len(copied)
+}
+
+// lockWith takes c's read lock and dst's write lock in address order so two opposite copies can't deadlock
+// returns a func that releases both
+func (c *Cache) lockWith(dst *Cache) func() {
+	if uintptr(unsafe.Pointer(c.cache)) < uintptr(unsafe.Pointer(dst.cache)) {
+		c.mut.RLock()
+		dst.mut.Lock()
+	} else {
+		dst.mut.Lock()
+		c.mut.RLock()
+	}
+	return func() {
+		dst.mut.Unlock()
+		c.mut.RUnlock()
+	}
+}
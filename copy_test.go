@@ -0,0 +1,112 @@
+package skyndiminni
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopyToDisjoint(t *testing.T) {
+	src, dst := newTestCache(t, NoExpiration), newTestCache(t, NoExpiration)
+	for _, k := range []string{"a", "b", "c", "gone"} {
+		if _, err := src.SetWithTTL(k, k, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expire(src, "gone")
+	backdate(src, "a", time.Minute)
+
+	if n := src.CopyTo(dst, []string{"a", "b", "gone", "missing"}, false); n != 2 {
+		t.Fatalf("CopyTo copied %d keys, want 2", n)
+	}
+	for _, k := range []string{"a", "b"} {
+		orig, _ := src.Get(k)
+		item, err := dst.Get(k)
+		if err != nil || item.Value != k {
+			t.Fatalf("dst.Get(%q) = %v, %v", k, item, err)
+		}
+		if item.Expiration != orig.Expiration || item.creationTime != orig.creationTime {
+			t.Errorf("%s copied with expiration %d and creation %d, want %d and %d", k, item.Expiration, item.creationTime, orig.Expiration, orig.creationTime)
+		}
+	}
+	if _, err := dst.Get("c"); err == nil {
+		t.Fatal("unlisted key was copied")
+	}
+	// the source is left as it was
+	if n := src.ItemCount(); n != 3 {
+		t.Fatalf("source has %d items after the copy", n)
+	}
+}
+
+func TestCopyToCollisions(t *testing.T) {
+	for _, overwrite := range []bool{false, true} {
+		src, dst := newTestCache(t, NoExpiration), newTestCache(t, NoExpiration)
+		for _, k := range []string{"live", "expired", "new"} {
+			if _, err := src.SetWithTTL(k, "src", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, k := range []string{"live", "expired"} {
+			if _, err := dst.SetWithTTL(k, "dst", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expire(dst, "expired")
+
+		want := map[string]string{"live": "dst", "expired": "src", "new": "src"}
+		copied := 2
+		if overwrite {
+			want["live"] = "src"
+			copied = 3
+		}
+		if n := src.CopyTo(dst, []string{"live", "expired", "new"}, overwrite); n != copied {
+			t.Errorf("overwrite %v: copied %d keys, want %d", overwrite, n, copied)
+		}
+		for k, v := range want {
+			if item, err := dst.Get(k); err != nil || item.Value != v {
+				t.Errorf("overwrite %v: dst.Get(%q) = %v, %v, want %s", overwrite, k, item, err, v)
+			}
+		}
+	}
+}
+
+func TestCopyToSelf(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetWithTTL("k", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.CopyTo(c, []string{"k"}, true); n != 0 {
+		t.Fatalf("CopyTo itself copied %d keys", n)
+	}
+}
+
+func TestCopyToOpposite(t *testing.T) {
+	a, b := newTestCache(t, NoExpiration), newTestCache(t, NoExpiration)
+	keys := []string{"x", "y"}
+	for _, k := range keys {
+		if _, err := a.SetWithTTL(k, "a", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.SetWithTTL(k, "b", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// copies in both directions take the locks in the same order so they don't deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				a.CopyTo(b, keys, true)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				b.CopyTo(a, keys, true)
+			}
+		}()
+	}
+	waitTimeout(t, &wg)
+}
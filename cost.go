@@ -0,0 +1,48 @@
+package skyndiminni
+
+import (
+	"errors"
+	"time"
+)
+
+// WithCostTTLScaling adjusts the ttl of SetWithCost from the item's cost
+// fn gets the cost and the ttl with 0 resolved to the default expiration, NoExpiration is never scaled
+func WithCostTTLScaling(fn func(cost int64, baseTTL time.Duration) time.Duration) Option {
+	return func(c *cache) {
+		c.costTTL = fn
+	}
+}
+
+// SetWithCost works like SetWithTTL but uses cost instead of the sizer as the size of the item
+// for the max bytes and max value size limits, the ttl is adjusted by WithCostTTLScaling
+func (c *Cache) SetWithCost(key string, value interface{}, cost int64, ttl time.Duration) (*Item, error) {
+	if cost < 0 {
+		return nil, errors.New("cost must not be negative")
+	}
+	if ttl == 0 {
+		ttl = c.defaultExpr
+	}
+	if c.costTTL != nil && ttl > 0 {
+		var scaled time.Duration
+		if err := c.safeCall("cost ttl scaling", func() { scaled = c.costTTL(cost, ttl) }); err != nil {
+			return nil, err
+		}
+		ttl = scaled
+	}
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+		size:       cost,
+		rawSize:    cost,
+		sized:      true,
+	}
+	if c.setOverwrite {
+		return c.set(key, item)
+	}
+	return c.add(key, item)
+}
@@ -0,0 +1,54 @@
+package skyndiminni
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCostTTLScaling(t *testing.T) {
+	c := newTestCache(t, time.Hour, WithCostTTLScaling(func(cost int64, baseTTL time.Duration) time.Duration {
+		if cost > 100 {
+			return baseTTL / 2
+		}
+		return baseTTL
+	}))
+	tests := []struct {
+		key  string
+		cost int64
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{key: "cheap", cost: 10, ttl: 2 * time.Hour, want: 2 * time.Hour},
+		{key: "costly", cost: 1000, ttl: 2 * time.Hour, want: time.Hour},
+		{key: "default", cost: 1000, ttl: 0, want: 30 * time.Minute},
+		{key: "immortal", cost: 1000, ttl: NoExpiration, want: NoExpiration},
+	}
+	for _, tt := range tests {
+		item, err := c.SetWithCost(tt.key, 1, tt.cost, tt.ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == NoExpiration {
+			if item.Expiration != int64(NoExpiration) {
+				t.Fatalf("%s: Expiration = %d, want NoExpiration", tt.key, item.Expiration)
+			}
+			continue
+		}
+		if diff := time.Until(item.ExpiresAt()) - tt.want; diff < -time.Second || diff > time.Second {
+			t.Fatalf("%s: expires in %v, want %v", tt.key, time.Until(item.ExpiresAt()), tt.want)
+		}
+	}
+}
+
+func TestSetWithCostCountsCost(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxBytes(100))
+	if _, err := c.SetWithCost("k", "tiny", 60, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Stats().Bytes; got != 60 {
+		t.Fatalf("Bytes = %d, want the cost", got)
+	}
+	if _, err := c.SetWithCost("neg", 1, -1, time.Hour); err == nil {
+		t.Fatal("negative cost accepted")
+	}
+}
@@ -0,0 +1,89 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// lockRetryMax is the longest wait between attempts to take the lock for SetCtx and GetCtx
+const lockRetryMax = time.Millisecond
+
+// SetCtx works like SetWithTTL but gives up with the context error if ctx is done before the write lock is taken
+// the lock is polled so on a busy cache it can lose out to callers blocking on it
+func (c *Cache) SetCtx(ctx context.Context, key string, value interface{}, ttl time.Duration) (*Item, error) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	}
+	if err := c.measure(item); err != nil {
+		return nil, err
+	}
+
+	if err := tryLock(ctx, c.mut.TryLock); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var removed []evictedItem
+	if existing := c.items[key]; existing != nil {
+		if !existing.IsExpired(now) && !c.setOverwrite {
+			c.mut.Unlock()
+			return existing.view(), errors.New("key already exists")
+		}
+		if existing.IsExpired(now) {
+			removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+		}
+	}
+	item.creationTime = now.Unix()
+	evicted, err := c.store(key, item, now)
+	removed = append(removed, evicted...)
+	c.mut.Unlock()
+	c.evicted(removed)
+	if err != nil {
+		return nil, err
+	}
+	return item.view(), c.publish(key)
+}
+
+// GetCtx works like Get but gives up with the context error if ctx is done before the read lock is taken
+// expired items are left for the cleanup instead of being removed, and WithBulkHydration is not used
+func (c *Cache) GetCtx(ctx context.Context, key string) (*Item, error) {
+	if err := tryLock(ctx, c.mut.TryRLock); err != nil {
+		return nil, err
+	}
+	item := c.items[key]
+	c.mut.RUnlock()
+	if item == nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, ErrKeyNotFound
+	}
+	if item.IsExpired(time.Now()) {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, ErrKeyExpired
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return item.view(), nil
+}
+
+// tryLock calls try until it takes the lock or ctx is done, backing off between attempts
+func tryLock(ctx context.Context, try func() bool) error {
+	wait := 10 * time.Microsecond
+	for !try() {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if wait *= 2; wait > lockRetryMax {
+			wait = lockRetryMax
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetCtxDeadlineWhileLocked(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	locked, unlock := make(chan struct{}), make(chan struct{})
+	go c.WithLock(func(tx *Tx) {
+		close(locked)
+		<-unlock
+	})
+	<-locked
+
+	for name, call := range map[string]func(ctx context.Context) error{
+		"SetCtx": func(ctx context.Context) error {
+			_, err := c.SetCtx(ctx, "k", 1, time.Minute)
+			return err
+		},
+		"GetCtx": func(ctx context.Context) error {
+			_, err := c.GetCtx(ctx, "k")
+			return err
+		},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		err := call(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("%s err = %v, want the context deadline", name, err)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("%s blocked for %v", name, d)
+		}
+	}
+	close(unlock)
+
+	// once the lock is free both go through
+	if _, err := c.SetCtx(context.Background(), "k", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := c.GetCtx(context.Background(), "k"); err != nil || item.Value != 1 {
+		t.Fatalf("GetCtx = %v, %v", item, err)
+	}
+}
+
+func TestGetCtxMissAndExpired(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetCtx(context.Background(), "k", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "k")
+	if _, err := c.GetCtx(context.Background(), "k"); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("GetCtx on an expired key err = %v", err)
+	}
+	if _, err := c.GetCtx(context.Background(), "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetCtx on a missing key err = %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package skyndiminni
+
+import (
+	"errors"
+	"time"
+)
+
+// debounced is a pending SetDebounced write waiting for its quiet period
+type debounced struct {
+	timer *time.Timer
+}
+
+// SetDebounced stores value under key for ttl once debounce passes without another SetDebounced for the key
+// a newer call replaces the pending value and restarts the wait, the write overwrites existing keys
+// the ttl counts from when the value is stored, errors storing it are sent to Errors
+// pending writes are dropped by Close
+func (c *Cache) SetDebounced(key string, value interface{}, ttl, debounce time.Duration) error {
+	if _, err := c.expiration(ttl); err != nil {
+		return err
+	}
+	c.debounceMut.Lock()
+	defer c.debounceMut.Unlock()
+	select {
+	case <-c.done:
+		return errors.New("cache is closed")
+	default:
+	}
+	if old := c.debounces[key]; old != nil {
+		old.timer.Stop()
+	}
+	d := &debounced{}
+	d.timer = time.AfterFunc(debounce, func() { c.applyDebounced(key, d, value, ttl) })
+	if c.debounces == nil {
+		c.debounces = make(map[string]*debounced)
+	}
+	c.debounces[key] = d
+	return nil
+}
+
+// applyDebounced stores the value of d if it is still the latest pending write for key
+func (c *cache) applyDebounced(key string, d *debounced, value interface{}, ttl time.Duration) {
+	c.debounceMut.Lock()
+	if c.debounces[key] != d {
+		c.debounceMut.Unlock()
+		return
+	}
+	delete(c.debounces, key)
+	c.debounceMut.Unlock()
+
+	expirationTime, _ := c.expiration(ttl)
+	if _, err := c.set(key, &Item{Value: value, Expiration: expirationTime}); err != nil {
+		c.reportError(err)
+	}
+}
+
+// stopDebounced drops every pending SetDebounced write
+func (c *cache) stopDebounced() {
+	c.debounceMut.Lock()
+	for k, d := range c.debounces {
+		d.timer.Stop()
+		delete(c.debounces, k)
+	}
+	c.debounceMut.Unlock()
+}
@@ -0,0 +1,55 @@
+package skyndiminni
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetDebouncedKeepsLatest(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	events, stop := c.Watch("progress")
+	defer stop()
+
+	for i := 1; i <= 10; i++ {
+		if err := c.SetDebounced("progress", i, time.Minute, 30*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Get("progress"); err == nil {
+		t.Fatal("debounced write was applied before the quiet period")
+	}
+
+	eventually(t, func() bool {
+		_, err := c.Get("progress")
+		return err == nil
+	})
+	if item, _ := c.Get("progress"); item.Value != 10 {
+		t.Fatalf("stored %v, want the last value 10", item.Value)
+	}
+	// no earlier write lands after the final one
+	time.Sleep(50 * time.Millisecond)
+	if got := drain(events); len(got) != 1 || got[0].Type != EventSet {
+		t.Fatalf("events = %v, want a single set", got)
+	}
+	if item, _ := c.Get("progress"); item.Value != 10 {
+		t.Fatalf("value changed to %v after the quiet period", item.Value)
+	}
+}
+
+func TestSetDebouncedCanceledByClose(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if err := c.SetDebounced("k", 1, time.Minute, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	time.Sleep(40 * time.Millisecond)
+	c.mut.RLock()
+	_, stored := c.items["k"]
+	c.mut.RUnlock()
+	if stored {
+		t.Fatal("pending write was applied after Close")
+	}
+	if err := c.SetDebounced("k", 1, time.Minute, time.Millisecond); err == nil {
+		t.Fatal("SetDebounced accepted a write on a closed cache")
+	}
+}
@@ -0,0 +1,42 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dumpValueMax is the number of characters of a value Dump prints before truncating it
+const dumpValueMax = 64
+
+// Dump returns a human readable listing of the non expired items sorted by key
+// each line has the key, the value formatted with %v, the remaining ttl and the age of the item
+func (c *Cache) Dump() string {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	now := time.Now()
+	keys := make([]string, 0, len(c.items))
+	for k, item := range c.items {
+		if !item.IsExpired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		item := c.items[k]
+		value := fmt.Sprintf("%v", item.view().Value)
+		if r := []rune(value); len(r) > dumpValueMax {
+			value = string(r[:dumpValueMax]) + "..."
+		}
+		ttl := "never"
+		if item.Expiration > 0 {
+			ttl = time.Unix(item.Expiration, 0).Sub(now).Truncate(time.Second).String()
+		}
+		age := now.Sub(time.Unix(item.creationTime, 0)).Truncate(time.Second)
+		fmt.Fprintf(&b, "%q: %s (ttl %s, age %s)\n", k, value, ttl, age)
+	}
+	return b.String()
+}
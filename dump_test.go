@@ -0,0 +1,40 @@
+package skyndiminni
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("live", "hello", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithTTL("immortal", 1, NoExpiration); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("long", strings.Repeat("x", 1000), inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("expired", "gone", anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := c.Dump()
+	lines := strings.Split(strings.TrimSuffix(dump, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Dump has %d lines:\n%s", len(lines), dump)
+	}
+	if !strings.Contains(dump, `"live": hello (ttl `) {
+		t.Fatalf("Dump does not list the live key:\n%s", dump)
+	}
+	if !strings.Contains(dump, `"immortal": 1 (ttl never`) {
+		t.Fatalf("Dump does not list the immortal key:\n%s", dump)
+	}
+	if strings.Contains(dump, "expired") {
+		t.Fatalf("Dump lists the expired key:\n%s", dump)
+	}
+	if strings.Contains(dump, strings.Repeat("x", dumpValueMax+1)) || !strings.Contains(dump, "...") {
+		t.Fatalf("Dump did not truncate the long value:\n%s", dump)
+	}
+}
@@ -0,0 +1,76 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/gob"
+)
+
+// encryptedValue is a value stored gob encoded and sealed with AES-GCM, the nonce is kept in front of the ciphertext
+type encryptedValue struct {
+	aead cipher.AEAD
+	data []byte
+}
+
+// encodedValue wraps a value so gob keeps its concrete type
+type encodedValue struct {
+	V interface{}
+}
+
+// WithEncryption encrypts every stored value with AES-GCM using key, which must be 16, 24 or 32 bytes long
+// values are gob encoded so their types must be registered with gob.Register like for GobCodec
+// each write pays for an encode and encryption and each read for a decryption and decode into a new copy of the value
+// keys are not encrypted and compression is not applied to encrypted values
+func WithEncryption(key []byte) Option {
+	return func(c *cache) {
+		c.encryptKey = key
+	}
+}
+
+// newAEAD returns the AES-GCM cipher for key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt replaces the item's value with its encrypted form, returns the encrypted length
+func (c *cache) encrypt(item *Item) (int64, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(encodedValue{V: item.Value}); err != nil {
+		return 0, err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+buf.Len()+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	data := c.aead.Seal(nonce, nonce, buf.Bytes(), nil)
+	item.Value = &encryptedValue{aead: c.aead, data: data}
+	return int64(len(data)), nil
+}
+
+// value decrypts and decodes the stored value, nil if the data is corrupt
+func (ev *encryptedValue) value() interface{} {
+	n := ev.aead.NonceSize()
+	if len(ev.data) < n {
+		return nil
+	}
+	plain, err := ev.aead.Open(nil, ev.data[:n], ev.data[n:], nil)
+	if err != nil {
+		return nil
+	}
+	var decoded encodedValue
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&decoded); err != nil {
+		return nil
+	}
+	return decoded.V
+}
+
+// storedSize returns the length of the encrypted data
+func (ev *encryptedValue) storedSize() int64 {
+	return int64(len(ev.data))
+}
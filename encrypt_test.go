@@ -0,0 +1,100 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithEncryption(testKey), WithSetOverwrite(true))
+	const secret = "correct horse battery staple"
+	if _, err := c.Set("k", secret, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c.mut.RLock()
+	ev, sealed := c.items["k"].Value.(*encryptedValue)
+	c.mut.RUnlock()
+	if !sealed {
+		t.Fatal("stored value is not encrypted")
+	}
+	if bytes.Contains(ev.data, []byte(secret)) {
+		t.Fatal("stored bytes contain the plaintext")
+	}
+	item, err := c.Get("k")
+	if err != nil || item.Value != secret {
+		t.Fatalf("Get = %v, %v", item, err)
+	}
+	// the same value sealed again uses a new nonce
+	if _, err := c.Set("k", secret, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c.mut.RLock()
+	again := c.items["k"].Value.(*encryptedValue)
+	c.mut.RUnlock()
+	if bytes.Equal(again.data, ev.data) {
+		t.Fatal("sealing the same value twice gave the same bytes")
+	}
+}
+
+func TestEncryptionBadKey(t *testing.T) {
+	if _, err := NewCache(NoExpiration, WithEncryption([]byte("short"))); err == nil {
+		t.Fatal("NewCache accepted a 5 byte key")
+	}
+}
+
+func TestIncrementOrCreateEncrypted(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithEncryption(testKey))
+	for want := int64(1); want <= 3; want++ {
+		n, err := c.IncrementOrCreate("n", 1, 1, NoExpiration)
+		if err != nil {
+			t.Fatalf("call %d: %v", want, err)
+		}
+		if n != want {
+			t.Fatalf("call %d returned %d", want, n)
+		}
+	}
+	c.mut.RLock()
+	_, sealed := c.items["n"].Value.(*encryptedValue)
+	c.mut.RUnlock()
+	if !sealed {
+		t.Fatal("incremented value was stored unencrypted")
+	}
+}
+
+func TestRoundTripperEncrypted(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	c := newTestCache(t, NoExpiration, WithEncryption(testKey))
+	client := &http.Client{Transport: c.RoundTripper(nil, time.Minute)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "body" {
+			t.Fatalf("body = %q", body)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("backend called %d times", n)
+	}
+	select {
+	case err := <-c.Errors():
+		t.Fatalf("unexpected error: %v", err)
+	default:
+	}
+}
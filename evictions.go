@@ -0,0 +1,70 @@
+package skyndiminni
+
+import "fmt"
+
+// WithAsyncEvictions runs eviction callbacks on a pool of workers fed by a queue of queueSize
+// instead of inline, so sweeps and deletes return without waiting on slow callbacks
+// when the queue is full the evicting call runs the callback itself, see WithDropEvictions
+// this slows the caller down like waiting for room would, but cannot deadlock when a callback writes to the cache
+// the workers drain the queue and stop on Close
+func WithAsyncEvictions(workers, queueSize int) Option {
+	return func(c *cache) {
+		c.evictWorkers = workers
+		c.evictQueueSize = queueSize
+	}
+}
+
+// WithDropEvictions makes a full async eviction queue drop callbacks instead of running them in the evicting call
+// each dropped eviction is reported on Errors
+func WithDropEvictions(drop bool) Option {
+	return func(c *cache) {
+		c.dropEvictions = drop
+	}
+}
+
+// startEvictionWorkers starts the goroutines running eviction callbacks from the queue
+func (c *cache) startEvictionWorkers() {
+	c.evictQueue = make(chan evictedItem, c.evictQueueSize)
+	for i := 0; i < c.evictWorkers; i++ {
+		c.evictWg.Add(1)
+		go func() {
+			defer c.evictWg.Done()
+			for e := range c.evictQueue {
+				c.runEvictionCallbacks(e)
+			}
+		}()
+	}
+}
+
+// stopEvictionWorkers closes the queue and waits for the workers to drain it
+func (c *cache) stopEvictionWorkers() {
+	c.evictMut.Lock()
+	c.evictStopped = true
+	close(c.evictQueue)
+	c.evictMut.Unlock()
+	c.evictWg.Wait()
+}
+
+// enqueueEvicted hands the removed items to the eviction workers and returns the ones the caller has to run itself
+// that is all of them once the workers are stopped and the ones that did not fit in the queue unless they are dropped
+// it never blocks as a callback on a worker evicting from the cache would wait on a queue only the workers drain
+func (c *cache) enqueueEvicted(removed []evictedItem) []evictedItem {
+	c.evictMut.RLock()
+	defer c.evictMut.RUnlock()
+	if c.evictStopped {
+		return removed
+	}
+	var inline []evictedItem
+	for _, e := range removed {
+		select {
+		case c.evictQueue <- e:
+		default:
+			if c.dropEvictions {
+				c.reportError(fmt.Errorf("eviction callback for key %q dropped, queue is full", e.key))
+			} else {
+				inline = append(inline, e)
+			}
+		}
+	}
+	return inline
+}
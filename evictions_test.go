@@ -0,0 +1,128 @@
+package skyndiminni
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncEvictionsRunCallbacks(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithAsyncEvictions(2, 4))
+	var wg sync.WaitGroup
+	wg.Add(10)
+	c.OnEvicted(func(key string, value interface{}) { wg.Done() })
+	for i := 0; i < 10; i++ {
+		key := string(rune('a' + i))
+		if _, err := c.Set(key, i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitTimeout(t, &wg)
+}
+
+func TestAsyncEvictionsSweepDoesNotWait(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithAsyncEvictions(2, 16))
+	var wg sync.WaitGroup
+	wg.Add(10)
+	c.OnEvicted(func(key string, value interface{}) {
+		defer wg.Done()
+		time.Sleep(50 * time.Millisecond)
+	})
+	for i := 0; i < 10; i++ {
+		if _, err := c.Set(string(rune('a'+i)), i, anHourAgo()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	c.initExpiration()
+	if took := time.Since(start); took > 200*time.Millisecond {
+		t.Fatalf("sweep took %v with slow callbacks", took)
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("sweep left %d items", n)
+	}
+	waitTimeout(t, &wg)
+}
+
+func TestAsyncEvictionCallbackCanDelete(t *testing.T) {
+	c, err := NewCache(NoExpiration, WithAsyncEvictions(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"a", "b", "c", "d"} {
+		if _, err := c.Set(k, k, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(4)
+	c.OnEvicted(func(key string, value interface{}) {
+		defer wg.Done()
+		// the worker fills the queue again from inside its own callback
+		switch key {
+		case "a":
+			c.Delete("c")
+			c.Delete("d")
+		}
+	})
+	if err := c.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	waitTimeout(t, &wg)
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hung")
+	}
+}
+
+func TestDropEvictions(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestCache(t, NoExpiration, WithAsyncEvictions(1, 1), WithDropEvictions(true))
+	c.OnEvicted(func(key string, value interface{}) { <-release })
+	defer close(release)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		if _, err := c.Set(k, k, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Delete(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case err := <-c.Errors():
+		if err == nil {
+			t.Fatal("nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no dropped eviction was reported")
+	}
+}
+
+// waitTimeout fails the test if wg is not done within 5 seconds
+func waitTimeout(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for callbacks")
+	}
+}
@@ -0,0 +1,57 @@
+package skyndiminni
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// ExpiringItem is a key and the time it expires, returned by ExpiringSoon
+type ExpiringItem struct {
+	Key       string
+	ExpiresAt time.Time
+}
+
+// expiringHeap is a max heap on expiration so the latest of the n kept items is popped first
+type expiringHeap []evictedItem
+
+func (h expiringHeap) Len() int            { return len(h) }
+func (h expiringHeap) Less(i, j int) bool  { return h[i].item.Expiration > h[j].item.Expiration }
+func (h expiringHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiringHeap) Push(x interface{}) { *h = append(*h, x.(evictedItem)) }
+func (h *expiringHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// ExpiringSoon returns up to n non expired keys with the nearest expirations, soonest first
+// keys that never expire are left out, it keeps a heap of n items instead of sorting the whole cache
+func (c *Cache) ExpiringSoon(n int) []ExpiringItem {
+	if n <= 0 {
+		return []ExpiringItem{}
+	}
+	c.mut.RLock()
+	now := time.Now()
+	h := make(expiringHeap, 0, n)
+	for k, item := range c.items {
+		if item.Expiration <= 0 || item.IsExpired(now) {
+			continue
+		}
+		if len(h) < n {
+			heap.Push(&h, evictedItem{k, item})
+		} else if item.Expiration < h[0].item.Expiration {
+			h[0] = evictedItem{k, item}
+			heap.Fix(&h, 0)
+		}
+	}
+	c.mut.RUnlock()
+
+	sort.Slice(h, func(i, j int) bool { return h[i].item.Expiration < h[j].item.Expiration })
+	soon := make([]ExpiringItem, len(h))
+	for i, e := range h {
+		soon[i] = ExpiringItem{Key: e.key, ExpiresAt: e.item.ExpiresAt()}
+	}
+	return soon
+}
@@ -0,0 +1,50 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiringSoon(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	ttls := map[string]time.Duration{
+		"d": 4 * time.Hour,
+		"a": time.Minute,
+		"c": 3 * time.Hour,
+		"e": 5 * time.Hour,
+		"b": time.Hour,
+	}
+	for k, ttl := range ttls {
+		if _, err := c.SetWithTTL(k, 1, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range []string{"immortal", "other immortal"} {
+		if _, err := c.SetWithTTL(k, 1, NoExpiration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.SetWithTTL("expired", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "expired")
+
+	keys := func(items []ExpiringItem) string {
+		var ks []string
+		for _, e := range items {
+			ks = append(ks, e.Key)
+		}
+		return fmt.Sprint(ks)
+	}
+	for n, want := range map[int]string{3: "[a b c]", 5: "[a b c d e]", 10: "[a b c d e]", 0: "[]"} {
+		if got := keys(c.ExpiringSoon(n)); got != want {
+			t.Errorf("ExpiringSoon(%d) = %s, want %s", n, got, want)
+		}
+	}
+
+	soon := c.ExpiringSoon(1)
+	if item, _ := c.Get("a"); len(soon) != 1 || !soon[0].ExpiresAt.Equal(item.ExpiresAt()) {
+		t.Fatalf("ExpiringSoon(1) = %v, want a at %v", soon, item.ExpiresAt())
+	}
+}
@@ -0,0 +1,64 @@
+package skyndiminni
+
+import "time"
+
+// EvictionReason is why a key was removed, see WithEvictionHistory
+type EvictionReason string
+
+const (
+	// EvictionExpired is an item removed after its expiration
+	EvictionExpired EvictionReason = "expired"
+	// EvictionMaxAge is an item removed for being older than WithMaxAge
+	EvictionMaxAge EvictionReason = "max age"
+	// EvictionCapacity is an item evicted to make room under WithMaxItems or WithMaxBytes or by Evict
+	EvictionCapacity EvictionReason = "capacity"
+	// EvictionDeleted is an item removed by Delete, Flush or another explicit removal
+	EvictionDeleted EvictionReason = "deleted"
+	// EvictionInvalidated is an item removed by an invalidation from the Broadcaster
+	EvictionInvalidated EvictionReason = "invalidated"
+	// EvictionReplaced is an item removed because Rename moved another item onto its key
+	EvictionReplaced EvictionReason = "replaced"
+)
+
+// EvictionRecord is a removed key kept by WithEvictionHistory
+type EvictionRecord struct {
+	Key    string
+	Reason EvictionReason
+	Time   time.Time
+}
+
+// WithEvictionHistory keeps the last n removed keys with why and when they were removed for RecentEvictions
+// values replaced by a write of the same key are not removals and are not recorded
+func WithEvictionHistory(n int) Option {
+	return func(c *cache) {
+		c.history = nil
+		if n > 0 {
+			c.history = make([]EvictionRecord, n)
+		}
+	}
+}
+
+// RecentEvictions returns the removals kept by WithEvictionHistory, oldest first
+func (c *Cache) RecentEvictions() []EvictionRecord {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if !c.historyFull {
+		return append([]EvictionRecord(nil), c.history[:c.historyNext]...)
+	}
+	records := make([]EvictionRecord, 0, len(c.history))
+	records = append(records, c.history[c.historyNext:]...)
+	return append(records, c.history[:c.historyNext]...)
+}
+
+// recordEviction adds a removal to the history, the caller must hold the write lock
+func (c *cache) recordEviction(key string, reason EvictionReason) {
+	if len(c.history) == 0 || reason == "" {
+		return
+	}
+	c.history[c.historyNext] = EvictionRecord{Key: key, Reason: reason, Time: time.Now()}
+	c.historyNext++
+	if c.historyNext == len(c.history) {
+		c.historyNext = 0
+		c.historyFull = true
+	}
+}
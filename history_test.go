@@ -0,0 +1,70 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRecentEvictions(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxItems(2), WithEvictionHistory(4), WithSetOverwrite(true))
+	set := func(k string) {
+		if _, err := c.Set(k, k, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set("a")
+	set("b")
+	expire(c, "a")
+	c.initExpiration()
+	backdate(c, "b", time.Minute)
+	set("c")
+	set("d")
+	if err := c.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	// overwriting a key is not a removal
+	set("d")
+	if err := c.Delete("d"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := func() string {
+		var rs []string
+		for _, r := range c.RecentEvictions() {
+			rs = append(rs, r.Key+" "+string(r.Reason))
+		}
+		return fmt.Sprint(rs)
+	}
+	if got := records(); got != "[a expired b capacity c deleted d deleted]" {
+		t.Fatalf("history = %s", got)
+	}
+
+	// the ring keeps the latest removals once it is full
+	set("e")
+	if err := c.Delete("e"); err != nil {
+		t.Fatal(err)
+	}
+	if got := records(); got != "[b capacity c deleted d deleted e deleted]" {
+		t.Fatalf("history after wrapping = %s", got)
+	}
+	rs := c.RecentEvictions()
+	for i := 1; i < len(rs); i++ {
+		if rs[i].Time.Before(rs[i-1].Time) {
+			t.Fatalf("record %d at %v is before record %d at %v", i, rs[i].Time, i-1, rs[i-1].Time)
+		}
+	}
+}
+
+func TestRecentEvictionsOff(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if rs := c.RecentEvictions(); len(rs) != 0 {
+		t.Fatalf("history without WithEvictionHistory = %v", rs)
+	}
+}
@@ -0,0 +1,164 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cachedResponse is a response stored by RoundTripper with its body read into memory
+type cachedResponse struct {
+	status     string
+	statusCode int
+	proto      string
+	protoMajor int
+	protoMinor int
+	header     http.Header
+	body       []byte
+}
+
+// gobResponse is a cachedResponse with exported fields so gob can encode it
+type gobResponse struct {
+	Status     string
+	StatusCode int
+	Proto      string
+	ProtoMajor int
+	ProtoMinor int
+	Header     http.Header
+	Body       []byte
+}
+
+func init() {
+	// stored responses are gob encoded by WithEncryption and GobCodec
+	gob.Register(&cachedResponse{})
+}
+
+// GobEncode encodes the response through gobResponse
+func (cr *cachedResponse) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(gobResponse{
+		Status:     cr.status,
+		StatusCode: cr.statusCode,
+		Proto:      cr.proto,
+		ProtoMajor: cr.protoMajor,
+		ProtoMinor: cr.protoMinor,
+		Header:     cr.header,
+		Body:       cr.body,
+	})
+	return buf.Bytes(), err
+}
+
+// GobDecode decodes a response encoded by GobEncode
+func (cr *cachedResponse) GobDecode(data []byte) error {
+	var g gobResponse
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return err
+	}
+	*cr = cachedResponse{
+		status:     g.Status,
+		statusCode: g.StatusCode,
+		proto:      g.Proto,
+		protoMajor: g.ProtoMajor,
+		protoMinor: g.ProtoMinor,
+		header:     g.Header,
+		body:       g.Body,
+	}
+	return nil
+}
+
+// roundTripper is the http.RoundTripper returned by Cache.RoundTripper
+type roundTripper struct {
+	c    *cache
+	base http.RoundTripper
+	ttl  time.Duration
+}
+
+// RoundTripper returns an http.RoundTripper caching successful GET responses by request URL for ttl
+// other requests and responses are passed to base, http.DefaultTransport if base is nil
+// requests with an Authorization header and responses with Cache-Control: private are never cached or served from the cache
+// as the client may be shared between users, neither are requests and responses with Cache-Control: no-store
+func (c *Cache) RoundTripper(base http.RoundTripper, ttl time.Duration) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{
+		c:    c.cache,
+		base: base,
+		ttl:  ttl,
+	}
+}
+
+// RoundTrip serves the request from the cache or from the base round tripper
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" || uncacheable(req.Header) {
+		return rt.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	if item, err := rt.c.get(key); err == nil {
+		if cached, ok := item.Value.(*cachedResponse); ok {
+			return cached.response(req), nil
+		}
+	}
+
+	resp, err := rt.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || uncacheable(resp.Header) {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedResponse{
+		status:     resp.Status,
+		statusCode: resp.StatusCode,
+		proto:      resp.Proto,
+		protoMajor: resp.ProtoMajor,
+		protoMinor: resp.ProtoMinor,
+		header:     resp.Header.Clone(),
+		body:       body,
+	}
+	if expirationTime, err := rt.c.expiration(rt.ttl); err == nil {
+		if _, err := rt.c.set(key, &Item{Value: cached, Expiration: expirationTime}); err != nil {
+			rt.c.reportError(err)
+		}
+	}
+	return cached.response(req), nil
+}
+
+// response builds a new response for req with a fresh reader over the stored body
+func (cr *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        cr.status,
+		StatusCode:    cr.statusCode,
+		Proto:         cr.proto,
+		ProtoMajor:    cr.protoMajor,
+		ProtoMinor:    cr.protoMinor,
+		Header:        cr.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cr.body)),
+		ContentLength: int64(len(cr.body)),
+		Request:       req,
+	}
+}
+
+// uncacheable reports if the Cache-Control header has the no-store or private directive
+// private may carry a list of fields, private="Set-Cookie", that is treated the same as a bare private
+func uncacheable(h http.Header) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name := strings.TrimSpace(directive)
+			if i := strings.IndexByte(name, '='); i >= 0 {
+				name = strings.TrimSpace(name[:i])
+			}
+			if strings.EqualFold(name, "no-store") || strings.EqualFold(name, "private") {
+				return true
+			}
+		}
+	}
+	return false
+}
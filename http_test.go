@@ -0,0 +1,149 @@
+package skyndiminni
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer serves a fixed body and counts the requests it gets
+// a request for /private is answered with Cache-Control: private, /nostore with no-store
+func countingServer(t *testing.T) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "max-age=60, private")
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		io.WriteString(w, "body "+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRoundTripper(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		calls  int32
+	}{
+		{name: "cached", method: http.MethodGet, path: "/", calls: 1},
+		{name: "post", method: http.MethodPost, path: "/", calls: 2},
+		{name: "not ok", method: http.MethodGet, path: "/missing", calls: 2},
+		{name: "no-store response", method: http.MethodGet, path: "/nostore", calls: 2},
+		{name: "private response", method: http.MethodGet, path: "/private", calls: 2},
+		{name: "no-store request", method: http.MethodGet, path: "/", header: http.Header{"Cache-Control": {"no-store"}}, calls: 2},
+		{name: "authorization", method: http.MethodGet, path: "/", header: http.Header{"Authorization": {"Bearer user"}}, calls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := countingServer(t)
+			c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+			client := &http.Client{Transport: c.RoundTripper(nil, time.Minute)}
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for k, v := range tt.header {
+					req.Header[k] = v
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if !strings.HasPrefix(string(body), "body ") {
+					t.Fatalf("body = %q", body)
+				}
+			}
+			if n := atomic.LoadInt32(calls); n != tt.calls {
+				t.Fatalf("backend called %d times, want %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRoundTripperAuthorizedResponseNotShared(t *testing.T) {
+	srv, calls := countingServer(t)
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	client := &http.Client{Transport: c.RoundTripper(nil, time.Minute)}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer alice")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Fatalf("anonymous request was served the authorized response, backend called %d times", n)
+	}
+}
+
+// fakeTransport answers every request itself and counts them
+type fakeTransport struct {
+	calls int32
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader("hello " + req.URL.Path)),
+		Request:    req,
+	}, nil
+}
+
+func TestRoundTripperServesFromCache(t *testing.T) {
+	base := &fakeTransport{}
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	rt := c.RoundTripper(base, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.test/a", nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "hello /a" || resp.Header.Get("Content-Type") != "text/plain" {
+			t.Fatalf("response %d: body %q, header %v", i, body, resp.Header)
+		}
+	}
+	if n := atomic.LoadInt32(&base.calls); n != 1 {
+		t.Fatalf("base called %d times, want 1", n)
+	}
+
+	// once the entry expires the request goes to base again
+	expire(c, "http://example.test/a")
+	req, _ := http.NewRequest(http.MethodGet, "http://example.test/a", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&base.calls); n != 2 {
+		t.Fatalf("base called %d times after expiry, want 2", n)
+	}
+}
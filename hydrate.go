@@ -0,0 +1,99 @@
+package skyndiminni
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// HydrationWindow is how long misses are collected before they are fetched together by WithBulkHydration
+const HydrationWindow = 10 * time.Millisecond
+
+// hydrationBatch is a set of missed keys waiting for one bulk fetch
+type hydrationBatch struct {
+	keys  map[string]struct{}
+	done  chan struct{}
+	items map[string]*Item
+	err   error
+}
+
+// WithBulkHydration makes Get misses within HydrationWindow of each other share one call to fetch
+// fetched values are stored with the default expiration, keys missing from the result stay misses
+// this avoids a stampede of single fetches against the backing store on a cold start
+func WithBulkHydration(fetch func(keys []string) (map[string]interface{}, error)) Option {
+	return func(c *cache) {
+		c.hydrator = fetch
+	}
+}
+
+// hydrate adds the key to the pending batch and waits for it to be fetched
+// returns a miss straight away while the store breaker is open
+func (c *cache) hydrate(key string) (*Item, error) {
+	if !c.breaker.allow(time.Now()) {
+		return nil, ErrKeyNotFound
+	}
+
+	c.hydrateMut.Lock()
+	b := c.hydration
+	if b == nil {
+		b = &hydrationBatch{
+			keys: make(map[string]struct{}),
+			done: make(chan struct{}),
+		}
+		c.hydration = b
+		time.AfterFunc(HydrationWindow, func() { c.fetchHydration(b) })
+	}
+	b.keys[key] = struct{}{}
+	c.hydrateMut.Unlock()
+
+	<-b.done
+	if b.err != nil {
+		return nil, b.err
+	}
+	item := b.items[key]
+	if item == nil {
+		return nil, ErrKeyNotFound
+	}
+	return item, nil
+}
+
+// fetchHydration closes the batch to new keys, fetches them and stores the values
+func (c *cache) fetchHydration(b *hydrationBatch) {
+	c.hydrateMut.Lock()
+	if c.hydration == b {
+		c.hydration = nil
+	}
+	keys := make([]string, 0, len(b.keys))
+	for k := range b.keys {
+		keys = append(keys, k)
+	}
+	c.hydrateMut.Unlock()
+
+	epoch := atomic.LoadInt64(&c.epoch)
+	var values map[string]interface{}
+	// the fetch serves every Get in the batch so its span has no single caller to be a child of
+	_, end := c.startSpan(context.Background(), "bulk hydration", "")
+	start := c.startOp()
+	if err := c.safeCall("bulk hydration", func() { values, b.err = c.hydrator(keys) }); err != nil {
+		b.err = err
+	}
+	c.endOp("bulk hydration", start)
+	end(false, b.err)
+	c.breaker.record(b.err, time.Now())
+	if b.err == nil {
+		expirationTime, _ := c.expiration(0)
+		b.items = make(map[string]*Item, len(values))
+		for k, v := range values {
+			item, err := c.put(k, &Item{
+				Value:      v,
+				Expiration: expirationTime,
+			}, epoch)
+			if err != nil {
+				c.reportError(err)
+				continue
+			}
+			b.items[k] = item
+		}
+	}
+	close(b.done)
+}
@@ -0,0 +1,80 @@
+package skyndiminni
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkHydrationBatchesMisses(t *testing.T) {
+	var mut sync.Mutex
+	var batches [][]string
+	c := newTestCache(t, time.Hour, WithBulkHydration(func(keys []string) (map[string]interface{}, error) {
+		mut.Lock()
+		batches = append(batches, keys)
+		mut.Unlock()
+		values := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			if k != "absent" {
+				values[k] = "value " + k
+			}
+		}
+		return values, nil
+	}))
+
+	keys := []string{"absent"}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, fmt.Sprint(i))
+	}
+	// the misses are released together so they land in one HydrationWindow
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			<-start
+			item, err := c.Get(k)
+			if k == "absent" {
+				if !errors.Is(err, ErrKeyNotFound) {
+					t.Errorf("Get(absent) err = %v", err)
+				}
+				return
+			}
+			if err != nil || item.Value != "value "+k {
+				t.Errorf("Get(%s) = %v, %v", k, item, err)
+			}
+		}(k)
+	}
+	close(start)
+	wg.Wait()
+
+	mut.Lock()
+	defer mut.Unlock()
+	if len(batches) != 1 {
+		t.Fatalf("bulk fetch called %d times, want once", len(batches))
+	}
+	got := append([]string(nil), batches[0]...)
+	sort.Strings(got)
+	sort.Strings(keys)
+	if fmt.Sprint(got) != fmt.Sprint(keys) {
+		t.Fatalf("bulk fetch got %v, want %v", got, keys)
+	}
+	// the fetched values are stored
+	if n := c.ItemCount(); n != 20 {
+		t.Fatalf("ItemCount = %d after hydration", n)
+	}
+}
+
+func TestBulkHydrationError(t *testing.T) {
+	errFetch := errors.New("store down")
+	c := newTestCache(t, time.Hour, WithBulkHydration(func(keys []string) (map[string]interface{}, error) {
+		return nil, errFetch
+	}))
+	if _, err := c.Get("k"); !errors.Is(err, errFetch) {
+		t.Fatalf("Get err = %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package skyndiminni
+
+import (
+	"errors"
+	"time"
+)
+
+// IncrementOrCreate adds delta to the integer stored at key, or creates the key with initial and ttl if it does not exist
+// the value keeps its integer type and the key keeps its expiration, returns the resulting value
+// returns an error if the existing value is not an integer
+func (c *Cache) IncrementOrCreate(key string, delta, initial int64, ttl time.Duration) (int64, error) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return 0, err
+	}
+	created := &Item{
+		Value:      initial,
+		Expiration: expirationTime,
+	}
+	if err := c.measure(created); err != nil {
+		return 0, err
+	}
+
+	c.mut.Lock()
+	now := time.Now()
+	var removed []evictedItem
+	item := c.items[key]
+	if item != nil && item.IsExpired(now) {
+		removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+		item = nil
+	}
+
+	var n int64
+	if item == nil {
+		n = initial
+		created.creationTime = now.Unix()
+		evicted, err := c.store(key, created, now)
+		removed = append(removed, evicted...)
+		if err != nil {
+			c.mut.Unlock()
+			c.evicted(removed)
+			return 0, err
+		}
+	} else {
+		value, sum, err := addInt(item.view().Value, delta)
+		if err != nil {
+			c.mut.Unlock()
+			c.evicted(removed)
+			return 0, err
+		}
+		next := item.clone()
+		next.Value = value
+		// the new value is compressed or encrypted again, its size is kept as an integer does not change size
+		if err := c.measure(next); err != nil {
+			c.mut.Unlock()
+			c.evicted(removed)
+			return 0, err
+		}
+		c.insert(key, next)
+		n = sum
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+	return n, c.publish(key)
+}
+
+// addInt adds delta to an integer value of any size, returning the sum in the value's type and as an int64
+func addInt(value interface{}, delta int64) (interface{}, int64, error) {
+	switch v := value.(type) {
+	case int:
+		v += int(delta)
+		return v, int64(v), nil
+	case int8:
+		v += int8(delta)
+		return v, int64(v), nil
+	case int16:
+		v += int16(delta)
+		return v, int64(v), nil
+	case int32:
+		v += int32(delta)
+		return v, int64(v), nil
+	case int64:
+		v += delta
+		return v, v, nil
+	case uint:
+		v += uint(delta)
+		return v, int64(v), nil
+	case uint8:
+		v += uint8(delta)
+		return v, int64(v), nil
+	case uint16:
+		v += uint16(delta)
+		return v, int64(v), nil
+	case uint32:
+		v += uint32(delta)
+		return v, int64(v), nil
+	case uint64:
+		v += uint64(delta)
+		return v, int64(v), nil
+	}
+	return nil, 0, errors.New("value is not an integer")
+}
@@ -0,0 +1,74 @@
+package skyndiminni
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrementOrCreate(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if n, err := c.IncrementOrCreate("n", 5, 10, time.Hour); err != nil || n != 10 {
+		t.Fatalf("create = %d, %v, want 10", n, err)
+	}
+	created, _ := c.Get("n")
+	for want := int64(15); want <= 25; want += 5 {
+		if n, err := c.IncrementOrCreate("n", 5, 10, time.Minute); err != nil || n != want {
+			t.Fatalf("increment = %d, %v, want %d", n, err, want)
+		}
+	}
+	item, _ := c.Get("n")
+	if item.Value != int64(25) {
+		t.Fatalf("stored %T %v", item.Value, item.Value)
+	}
+	if item.Expiration != created.Expiration {
+		t.Fatal("increment changed the expiration")
+	}
+}
+
+func TestIncrementOrCreateKeepsType(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("n", uint8(1), inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.IncrementOrCreate("n", 2, 0, time.Hour); err != nil || n != 3 {
+		t.Fatalf("increment = %d, %v", n, err)
+	}
+	if item, _ := c.Get("n"); item.Value != uint8(3) {
+		t.Fatalf("stored %T %v", item.Value, item.Value)
+	}
+}
+
+func TestIncrementOrCreateNotInteger(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("s", "text", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.IncrementOrCreate("s", 1, 0, time.Hour); err == nil {
+		t.Fatal("incremented a string")
+	}
+	if item, _ := c.Get("s"); item.Value != "text" {
+		t.Fatalf("value changed to %v", item.Value)
+	}
+}
+
+func TestIncrementOrCreateConcurrent(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := c.IncrementOrCreate("n", 1, 1, time.Hour); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if item, _ := c.Get("n"); item.Value != int64(800) {
+		t.Fatalf("counter = %v, want 800", item.Value)
+	}
+}
@@ -0,0 +1,88 @@
+package skyndiminni
+
+import "time"
+
+// valueIndex maps index keys to the cache keys whose values produce them
+type valueIndex struct {
+	fn   func(value interface{}) (indexKey string, ok bool)
+	keys map[string]map[string]struct{}
+	of   map[string]string
+}
+
+// RegisterIndex adds an index called name, fn returns the index key for a value or false to leave it out
+// existing items are indexed straight away and the index is kept up to date as keys are set and removed
+// fn is called under the write lock and must not use the cache, registering a name again replaces the index
+func (c *Cache) RegisterIndex(name string, fn func(value interface{}) (indexKey string, ok bool)) {
+	idx := &valueIndex{
+		fn:   fn,
+		keys: make(map[string]map[string]struct{}),
+		of:   make(map[string]string),
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.indexes == nil {
+		c.indexes = make(map[string]*valueIndex)
+	}
+	c.indexes[name] = idx
+	for k, item := range c.items {
+		c.indexItem(idx, k, item)
+	}
+}
+
+// ByIndex returns the non expired items whose values map to indexKey in the index called name
+// returns nil if there is no such index
+func (c *Cache) ByIndex(name, indexKey string) []*Item {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	idx := c.indexes[name]
+	if idx == nil {
+		return nil
+	}
+	now := time.Now()
+	items := make([]*Item, 0, len(idx.keys[indexKey]))
+	for k := range idx.keys[indexKey] {
+		if item := c.items[k]; item != nil && !item.IsExpired(now) {
+			items = append(items, item.view())
+		}
+	}
+	return items
+}
+
+// indexItem adds key to idx under the index key of the item's value, the caller must hold the write lock
+func (c *cache) indexItem(idx *valueIndex, key string, item *Item) {
+	var indexKey string
+	var ok bool
+	if err := c.safeCall("index function", func() { indexKey, ok = idx.fn(item.view().Value) }); err != nil || !ok {
+		return
+	}
+	keys := idx.keys[indexKey]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		idx.keys[indexKey] = keys
+	}
+	keys[key] = struct{}{}
+	idx.of[key] = indexKey
+}
+
+// unindexKey removes key from every index, the caller must hold the write lock
+func (c *cache) unindexKey(key string) {
+	for _, idx := range c.indexes {
+		indexKey, ok := idx.of[key]
+		if !ok {
+			continue
+		}
+		delete(idx.of, key)
+		delete(idx.keys[indexKey], key)
+		if len(idx.keys[indexKey]) == 0 {
+			delete(idx.keys, indexKey)
+		}
+	}
+}
+
+// reindexKey indexes key in every index under its new item, the caller must hold the write lock
+func (c *cache) reindexKey(key string, item *Item) {
+	c.unindexKey(key)
+	for _, idx := range c.indexes {
+		c.indexItem(idx, key, item)
+	}
+}
@@ -0,0 +1,79 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// indexedUser is a value indexed by team in the index tests
+type indexedUser struct {
+	Name string
+	Team string
+}
+
+// names returns the sorted names of the users in items
+func names(items []*Item) string {
+	var ns []string
+	for _, item := range items {
+		ns = append(ns, item.Value.(indexedUser).Name)
+	}
+	sort.Strings(ns)
+	return fmt.Sprint(ns)
+}
+
+func TestIndexByField(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	set := func(key, name, team string) {
+		if _, err := c.Set(key, indexedUser{Name: name, Team: team}, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set("1", "ann", "red")
+	// values the index function rejects are left out
+	if _, err := c.Set("other", 42, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterIndex("team", func(value interface{}) (string, bool) {
+		u, ok := value.(indexedUser)
+		return u.Team, ok
+	})
+	set("2", "bob", "red")
+	set("3", "cat", "blue")
+	set("4", "dan", "red")
+
+	if got := names(c.ByIndex("team", "red")); got != "[ann bob dan]" {
+		t.Fatalf("red = %s, want the item indexed at registration and the ones set after", got)
+	}
+	if got := names(c.ByIndex("team", "blue")); got != "[cat]" {
+		t.Fatalf("blue = %s", got)
+	}
+
+	// deleting, expiring and moving an item to another team keep the index consistent
+	if err := c.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "4")
+	set("1", "ann", "blue")
+	if got := names(c.ByIndex("team", "red")); got != "[]" {
+		t.Fatalf("red after changes = %s", got)
+	}
+	if got := names(c.ByIndex("team", "blue")); got != "[ann cat]" {
+		t.Fatalf("blue after changes = %s", got)
+	}
+
+	c.initExpiration()
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	idx := c.indexes["team"]
+	if _, ok := idx.keys["red"]; ok || len(idx.of) != 2 {
+		t.Fatalf("index still holds removed keys: %v, %v", idx.keys, idx.of)
+	}
+}
+
+func TestByIndexUnknown(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if items := c.ByIndex("missing", "x"); items != nil {
+		t.Fatalf("ByIndex on an unknown index = %v", items)
+	}
+}
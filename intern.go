@@ -0,0 +1,54 @@
+package skyndiminni
+
+// internedValue is a value shared by every item whose value has the same hash
+type internedValue struct {
+	hash  string
+	value interface{}
+	refs  int
+}
+
+// WithValueInterning stores values with the same hash once and shares them between keys
+// values are reference counted and released when the last key using them is removed
+// hasher must return the same string only for values that are interchangeable
+func WithValueInterning(hasher func(value interface{}) string) Option {
+	return func(c *cache) {
+		c.hasher = hasher
+		c.interned = make(map[string]*internedValue)
+	}
+}
+
+// intern replaces the item's value with the shared copy for its hash, the caller must hold the write lock
+func (c *cache) intern(item *Item) {
+	if c.hasher == nil || item.interned != nil {
+		return
+	}
+	if _, ok := item.Value.(storedValue); ok {
+		return
+	}
+	var hash string
+	if err := c.safeCall("value hasher", func() { hash = c.hasher(item.Value) }); err != nil {
+		return
+	}
+
+	v := c.interned[hash]
+	if v == nil {
+		v = &internedValue{hash: hash, value: item.Value}
+		c.interned[hash] = v
+	}
+	v.refs++
+	item.Value = v.value
+	item.interned = v
+}
+
+// release drops the item's reference to its shared value, the caller must hold the write lock
+func (c *cache) release(item *Item) {
+	v := item.interned
+	if v == nil {
+		return
+	}
+	item.interned = nil
+	v.refs--
+	if v.refs == 0 {
+		delete(c.interned, v.hash)
+	}
+}
@@ -0,0 +1,100 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func hashValue(v interface{}) string {
+	return fmt.Sprint(v)
+}
+
+func TestValueInterningSharesValues(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true), WithValueInterning(func(v interface{}) string {
+		return string(v.([]byte))
+	}))
+	for i := 0; i < 100; i++ {
+		// every key gets its own copy of the same contents
+		if _, err := c.Set(fmt.Sprint(i), bytes.Repeat([]byte("x"), 4096), inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, _ := c.Get("0")
+	for i := 1; i < 100; i++ {
+		item, _ := c.Get(fmt.Sprint(i))
+		if &item.Value.([]byte)[0] != &first.Value.([]byte)[0] {
+			t.Fatalf("key %d does not share the backing array", i)
+		}
+	}
+	c.mut.RLock()
+	shared, refs := len(c.interned), 0
+	for _, v := range c.interned {
+		refs = v.refs
+	}
+	c.mut.RUnlock()
+	if shared != 1 || refs != 100 {
+		t.Fatalf("%d interned values with %d refs, want 1 with 100", shared, refs)
+	}
+
+	// expiring, deleting and overwriting keys all drop their reference
+	for i := 0; i < 50; i++ {
+		expire(c, fmt.Sprint(i))
+	}
+	c.initExpiration()
+	for i := 50; i < 99; i++ {
+		if err := c.Delete(fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Set("99", []byte("other"), inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if len(c.interned) != 1 || c.interned["other"] == nil || c.interned["other"].refs != 1 {
+		t.Fatalf("interned values left: %v", c.interned)
+	}
+}
+
+func TestRenameInternedDoesNotRaceReaders(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithValueInterning(hashValue))
+	if _, err := c.Set("a", "shared", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	started, done := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			snap := c.Snapshot()
+			for _, k := range []string{"a", "b"} {
+				if item, ok := snap.Get(k); ok && item.Value != "shared" {
+					t.Errorf("value = %v", item.Value)
+				}
+			}
+		}
+	}()
+	<-started
+	for i := 0; i < 1000; i++ {
+		from, to := "a", "b"
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		if err := c.Rename(from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
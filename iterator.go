@@ -0,0 +1,35 @@
+package skyndiminni
+
+import "time"
+
+// Iterator walks the items of a cache one at a time, see Cache.Iterator
+type Iterator struct {
+	c    *cache
+	keys []string
+	next int
+}
+
+// Iterator returns an Iterator over the keys that are live now, in insertion order with WithOrderedKeys
+// only the keys are copied up front, each item is read when Next reaches it
+// keys added after the iterator is created are not visited and keys removed or expired since are skipped
+func (c *Cache) Iterator() *Iterator {
+	return &Iterator{
+		c:    c.cache,
+		keys: c.Keys(),
+	}
+}
+
+// Next returns the next live key and its item, ok is false once every key has been visited
+func (it *Iterator) Next() (key string, item *Item, ok bool) {
+	for it.next < len(it.keys) {
+		key = it.keys[it.next]
+		it.next++
+		it.c.mut.RLock()
+		item = it.c.items[key]
+		it.c.mut.RUnlock()
+		if item != nil && !item.IsExpired(time.Now()) {
+			return key, item.view(), true
+		}
+	}
+	return "", nil, false
+}
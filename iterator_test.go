@@ -0,0 +1,72 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIteratorVisitsEveryLiveKeyOnce(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	want := map[string]int{}
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprint(i)
+		if _, err := c.Set(k, i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		want[k] = i
+	}
+	for i := 100; i < 110; i++ {
+		if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		expire(c, fmt.Sprint(i))
+	}
+
+	visited := map[string]int{}
+	it := c.Iterator()
+	for {
+		key, item, ok := it.Next()
+		if !ok {
+			break
+		}
+		visited[key]++
+		if item.Value != want[key] {
+			t.Errorf("%s has value %v, want %d", key, item.Value, want[key])
+		}
+	}
+	if len(visited) != len(want) {
+		t.Fatalf("visited %d keys, want %d", len(visited), len(want))
+	}
+	for k, n := range visited {
+		if _, ok := want[k]; !ok || n != 1 {
+			t.Errorf("%s visited %d times, live = %v", k, n, ok)
+		}
+	}
+	if _, _, ok := it.Next(); ok {
+		t.Fatal("Next returned an item after the end")
+	}
+}
+
+func TestIteratorChangesAfterCreation(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithOrderedKeys())
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := c.Set(k, 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	it := c.Iterator()
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("d", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for k, _, ok := it.Next(); ok; k, _, ok = it.Next() {
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[a c]" {
+		t.Fatalf("visited %v, want the removed key skipped and the new key left out", keys)
+	}
+}
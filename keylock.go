@@ -0,0 +1,20 @@
+package skyndiminni
+
+import "sync"
+
+// keyLockStripes is the number of mutexes keys are hashed over for UpdateFunc
+const keyLockStripes = 256
+
+// keyLocks serializes work on the same key without holding the cache's lock
+type keyLocks [keyLockStripes]sync.Mutex
+
+// lock returns the mutex for the key, different keys may share a mutex
+func (l *keyLocks) lock(key string) *sync.Mutex {
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return &l[h%keyLockStripes]
+}
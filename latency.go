@@ -0,0 +1,51 @@
+package skyndiminni
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples is the number of most recent loader runs kept for LoaderLatencies
+const latencySamples = 1024
+
+// latencyRing keeps the most recent durations, recording is a couple of atomic operations without a lock
+type latencyRing struct {
+	next    int64
+	samples [latencySamples]int64
+}
+
+// LoaderLatencies returns the 50th, 95th and 99th percentile durations of the latest loader runs
+// the percentiles cover the last 1024 runs, all are 0 if no loader has run yet
+func (c *Cache) LoaderLatencies() (p50, p95, p99 time.Duration) {
+	samples := c.loadLatency.snapshot()
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	return percentile(samples, 50), percentile(samples, 95), percentile(samples, 99)
+}
+
+// record adds d to the ring, overwriting the oldest duration once it is full
+func (r *latencyRing) record(d time.Duration) {
+	i := atomic.AddInt64(&r.next, 1) - 1
+	atomic.StoreInt64(&r.samples[i%latencySamples], int64(d))
+}
+
+// snapshot returns the recorded durations sorted from shortest to longest
+func (r *latencyRing) snapshot() []time.Duration {
+	n := atomic.LoadInt64(&r.next)
+	if n > latencySamples {
+		n = latencySamples
+	}
+	samples := make([]time.Duration, n)
+	for i := range samples {
+		samples[i] = time.Duration(atomic.LoadInt64(&r.samples[i]))
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples
+}
+
+// percentile returns the p-th percentile of the sorted samples
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[(len(sorted)-1)*p/100]
+}
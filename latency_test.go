@@ -0,0 +1,53 @@
+package skyndiminni
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoaderLatencies(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if p50, p95, p99 := c.LoaderLatencies(); p50 != 0 || p95 != 0 || p99 != 0 {
+		t.Fatalf("latencies before any load = %v, %v, %v", p50, p95, p99)
+	}
+
+	// 18 fast runs and 2 slow ones put the median on a fast run and p95 and p99 on the slow ones
+	const fast, slow = time.Millisecond, 30 * time.Millisecond
+	for i := 0; i < 20; i++ {
+		d := fast
+		if i%10 == 9 {
+			d = slow
+		}
+		_, err := c.GetOrLoad(context.Background(), fmt.Sprint(i), time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+			time.Sleep(d)
+			return key, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p50, p95, p99 := c.LoaderLatencies()
+	if p50 < fast || p50 >= slow {
+		t.Errorf("p50 = %v, want a fast run", p50)
+	}
+	if p95 < slow || p99 < slow {
+		t.Errorf("p95 = %v, p99 = %v, want slow runs", p95, p99)
+	}
+}
+
+func TestLatencyRingKeepsLatest(t *testing.T) {
+	var r latencyRing
+	for i := 0; i < latencySamples; i++ {
+		r.record(time.Hour)
+	}
+	for i := 0; i < latencySamples; i++ {
+		r.record(time.Duration(i))
+	}
+	samples := r.snapshot()
+	if len(samples) != latencySamples || samples[len(samples)-1] != latencySamples-1 {
+		t.Fatalf("ring kept %d samples, longest %v", len(samples), samples[len(samples)-1])
+	}
+}
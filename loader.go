@@ -0,0 +1,256 @@
+package skyndiminni
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// loadCall is a loader run shared by every GetOrLoad call for the same key
+// waiters counts the calls that joined the run, it is guarded by loadMut
+type loadCall struct {
+	done    chan struct{}
+	item    *Item
+	err     error
+	stale   *Item
+	waiters int
+}
+
+// loadError is a failed load remembered until its window passes
+type loadError struct {
+	err   error
+	until time.Time
+	stale *Item
+}
+
+// loadRun is the latest loader run for a key kept for WithLoaderRateLimit
+type loadRun struct {
+	at   time.Time
+	item *Item
+}
+
+// WithErrorCaching makes GetOrLoad remember a failed load for ttl
+// calls for the key during that window return the same error without calling the loader again
+func WithErrorCaching(ttl time.Duration) Option {
+	return func(c *cache) {
+		c.errorTTL = ttl
+	}
+}
+
+// WithStaleOnError makes GetOrLoad return the expired item for the key instead of the loader error
+// expired items are kept until they are read or swept by the cleanup, so a stale item is not always there
+// the returned item is flagged by being expired, check it with IsExpired
+// with WithErrorCaching the stale item is also returned for the rest of the error window
+func WithStaleOnError(enabled bool) Option {
+	return func(c *cache) {
+		c.staleOnError = enabled
+	}
+}
+
+// WithLoaderRateLimit makes GetOrLoad call the loader for a key at most once every perKey
+// in between misses are served the item from the last load, even if it has expired since, or a miss if that load failed
+func WithLoaderRateLimit(perKey time.Duration) Option {
+	return func(c *cache) {
+		c.loadInterval = perKey
+	}
+}
+
+// WithMaxConcurrentLoads limits the number of loaders running at once across all keys to n
+// further loads wait for a running one to finish, or until their context is done
+// n of 0 or less is no limit
+func WithMaxConcurrentLoads(n int) Option {
+	return func(c *cache) {
+		c.loadSlots = nil
+		if n > 0 {
+			c.loadSlots = make(chan struct{}, n)
+		}
+	}
+}
+
+// GetOrLoad gets a non expired value based off provided key or calls loader to load it
+// the loaded value is stored with ttl, concurrent calls for the same key share a single loader run
+// a waiting call returns early with the context error if ctx is done, see WithMaxWaitersPerKey
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context, key string) (interface{}, error)) (*Item, error) {
+	item, _, err := c.getOrLoad(ctx, key, ttl, loader)
+	return item, err
+}
+
+// CacheAside returns the cached value for key or computes, stores with ttl and returns it
+// fromCache reports if the value was already cached, concurrent misses share a single compute call
+func (c *Cache) CacheAside(key string, ttl time.Duration, compute func() (interface{}, error)) (value interface{}, fromCache bool, err error) {
+	item, loaded, err := c.getOrLoad(context.Background(), key, ttl, func(ctx context.Context, key string) (interface{}, error) {
+		return compute()
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return item.Value, !loaded, nil
+}
+
+// getOrLoad is GetOrLoad also reporting if the item came from the loader
+func (c *cache) getOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context, key string) (interface{}, error)) (item *Item, loaded bool, err error) {
+	ctx, end := c.startSpan(ctx, "GetOrLoad", key)
+	defer func() { end(err == nil && !loaded, err) }()
+
+	if _, err := c.expiration(ttl); err != nil {
+		return nil, false, err
+	}
+	// the lookup removes an expired item so it has to be kept before
+	var stale *Item
+	if c.staleOnError {
+		stale = c.expired(key)
+	}
+	if item, err := c.lookup(key); err == nil {
+		return item, false, nil
+	}
+
+	c.loadMut.Lock()
+	if le, ok := c.loadErrs[key]; ok {
+		if time.Now().Before(le.until) {
+			c.loadMut.Unlock()
+			if le.stale != nil {
+				return le.stale.view(), false, nil
+			}
+			return nil, false, le.err
+		}
+		delete(c.loadErrs, key)
+	}
+	call, joined := c.loads[key]
+	if !joined && c.loadInterval > 0 {
+		now := time.Now()
+		if run, ok := c.loadRuns[key]; ok && now.Sub(run.at) < c.loadInterval {
+			c.loadMut.Unlock()
+			if run.item == nil {
+				return nil, false, ErrKeyNotFound
+			}
+			return run.item, false, nil
+		}
+		c.loadRuns[key] = loadRun{at: now}
+	}
+	if !joined {
+		call = &loadCall{done: make(chan struct{}), stale: stale}
+		c.loads[key] = call
+		c.loadMut.Unlock()
+		c.load(ctx, key, ttl, loader, call)
+	} else {
+		if c.maxWaiters > 0 && call.waiters >= c.maxWaiters {
+			c.loadMut.Unlock()
+			return nil, false, ErrTooManyWaiters
+		}
+		call.waiters++
+		c.loadMut.Unlock()
+	}
+
+	select {
+	case <-call.done:
+		return call.item, true, call.err
+	case <-ctx.Done():
+		if joined {
+			c.loadMut.Lock()
+			call.waiters--
+			c.loadMut.Unlock()
+		}
+		return nil, false, ctx.Err()
+	}
+}
+
+// load runs the loader for call and stores its result
+func (c *cache) load(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context, key string) (interface{}, error), call *loadCall) {
+	ctx, cancel := c.loadContext(ctx)
+	defer cancel()
+	if c.loadSlots != nil {
+		select {
+		case c.loadSlots <- struct{}{}:
+		case <-ctx.Done():
+			// the loader never ran so there is no error to remember
+			c.finishLoad(key, call, ctx.Err(), false)
+			return
+		}
+	}
+
+	// a flush while the loader runs discards its value, see put
+	epoch := atomic.LoadInt64(&c.epoch)
+	var value interface{}
+	var err error
+	ctx, end := c.startSpan(ctx, "loader", key)
+	start := c.startOp()
+	began := time.Now()
+	if perr := c.safeCall("loader", func() { value, err = loader(ctx, key) }); perr != nil {
+		err = perr
+	}
+	c.loadLatency.record(time.Since(began))
+	c.endOp("loader", start)
+	if c.loadSlots != nil {
+		<-c.loadSlots
+	}
+
+	if err == nil {
+		// the ttl was checked by GetOrLoad and counts from when the value was loaded
+		expirationTime, _ := c.expiration(ttl)
+		call.item, err = c.put(key, &Item{
+			Value:      value,
+			Expiration: expirationTime,
+		}, epoch)
+		if err == nil {
+			err = c.publish(key)
+		}
+	}
+	end(false, err)
+	c.finishLoad(key, call, err, true)
+}
+
+// finishLoad records the result of call and releases its waiters
+// a failure is kept for WithErrorCaching only if remember is set
+// a failure is replaced by the stale item of the call if there is one
+func (c *cache) finishLoad(key string, call *loadCall, err error, remember bool) {
+	call.err = err
+	if err != nil && call.stale != nil {
+		call.item, call.err = call.stale.view(), nil
+	}
+	c.loadMut.Lock()
+	delete(c.loads, key)
+	var drained chan struct{}
+	if len(c.loads) == 0 && c.drained != nil {
+		drained, c.drained = c.drained, nil
+	}
+	if run, ok := c.loadRuns[key]; ok && call.err == nil {
+		run.item = call.item
+		c.loadRuns[key] = run
+	}
+	if err != nil && remember && c.errorTTL > 0 {
+		c.loadErrs[key] = loadError{err: err, until: time.Now().Add(c.errorTTL), stale: call.stale}
+	} else if err == nil {
+		delete(c.loadErrs, key)
+	}
+	c.loadMut.Unlock()
+	close(call.done)
+	if drained != nil {
+		close(drained)
+	}
+}
+
+// expired returns the item for key if it is stored but expired
+func (c *cache) expired(key string) *Item {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if item := c.items[key]; item != nil && item.IsExpired(time.Now()) {
+		return item
+	}
+	return nil
+}
+
+// expireLoads forgets cached load errors and loader runs whose window has passed
+func (c *cache) expireLoads(now time.Time) {
+	c.loadMut.Lock()
+	for k, le := range c.loadErrs {
+		if !now.Before(le.until) {
+			delete(c.loadErrs, k)
+		}
+	}
+	for k, run := range c.loadRuns {
+		if now.Sub(run.at) >= c.loadInterval {
+			delete(c.loadRuns, k)
+		}
+	}
+	c.loadMut.Unlock()
+}
@@ -0,0 +1,312 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errLoad = errors.New("load failed")
+
+// countingLoader returns a loader counting its calls that fails while *fail is set
+func countingLoader(calls *int32, fail *int32) func(ctx context.Context, key string) (interface{}, error) {
+	return func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(calls, 1)
+		if atomic.LoadInt32(fail) == 1 {
+			return nil, errLoad
+		}
+		return "value", nil
+	}
+}
+
+func TestErrorCaching(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithErrorCaching(50*time.Millisecond))
+	var calls, fail int32 = 0, 1
+	loader := countingLoader(&calls, &fail)
+
+	for i := 0; i < 5; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("GetOrLoad err = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("loader called %d times inside the error window", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad err = %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("loader called %d times, want a retry after the window", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&fail, 0)
+	if item, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); err != nil || item.Value != "value" {
+		t.Fatalf("GetOrLoad = %v, %v", item, err)
+	}
+	c.loadMut.Lock()
+	_, cached := c.loadErrs["k"]
+	c.loadMut.Unlock()
+	if cached {
+		t.Fatal("a successful load left the error cached")
+	}
+}
+
+func TestErrorCachingOff(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var calls, fail int32 = 0, 1
+	loader := countingLoader(&calls, &fail)
+	for i := 0; i < 3; i++ {
+		c.GetOrLoad(context.Background(), "k", time.Minute, loader)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("loader called %d times without error caching", n)
+	}
+}
+
+func TestCacheAside(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var calls int32
+	compute := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "computed", nil
+	}
+
+	value, fromCache, err := c.CacheAside("k", time.Minute, compute)
+	if err != nil || value != "computed" || fromCache {
+		t.Fatalf("first CacheAside = %v, %v, %v", value, fromCache, err)
+	}
+	if s := c.Stats(); s.Hits != 0 || s.Misses != 1 {
+		t.Fatalf("after a miss hits = %d, misses = %d", s.Hits, s.Misses)
+	}
+	value, fromCache, err = c.CacheAside("k", time.Minute, compute)
+	if err != nil || value != "computed" || !fromCache {
+		t.Fatalf("second CacheAside = %v, %v, %v", value, fromCache, err)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Fatalf("after a hit hits = %d, misses = %d", s.Hits, s.Misses)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("compute called %d times", n)
+	}
+
+	if _, _, err := c.CacheAside("fails", time.Minute, func() (interface{}, error) { return nil, errLoad }); !errors.Is(err, errLoad) {
+		t.Fatalf("CacheAside err = %v", err)
+	}
+}
+
+func TestCacheAsideSharesConcurrentMisses(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var calls int32
+	release := make(chan struct{})
+	compute := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 1, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := c.CacheAside("k", time.Minute, compute); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("compute called %d times for concurrent misses", n)
+	}
+}
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	const limit = 3
+	c := newTestCache(t, NoExpiration, WithMaxConcurrentLoads(limit))
+	var running, peak int32
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return key, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprint("cold", i)
+			if item, err := c.GetOrLoad(context.Background(), key, time.Minute, loader); err != nil || item.Value != key {
+				t.Errorf("GetOrLoad(%s) = %v, %v", key, item, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if p := atomic.LoadInt32(&peak); p > limit || p == 0 {
+		t.Fatalf("%d loaders ran at once, limit %d", p, limit)
+	}
+}
+
+func TestMaxConcurrentLoadsContextWhileQueued(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxConcurrentLoads(1))
+	release := make(chan struct{})
+	go c.GetOrLoad(context.Background(), "busy", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		<-release
+		return 1, nil
+	})
+	defer close(release)
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.GetOrLoad(ctx, "queued", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		t.Error("loader ran without a free slot")
+		return 1, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetOrLoad err = %v", err)
+	}
+}
+
+func TestStaleOnError(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithStaleOnError(true), WithErrorCaching(time.Minute))
+	var calls, fail int32
+	loader := countingLoader(&calls, &fail)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "k")
+	atomic.StoreInt32(&fail, 1)
+
+	for i := 0; i < 2; i++ {
+		item, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader)
+		if err != nil || item.Value != "value" {
+			t.Fatalf("GetOrLoad = %v, %v, want the stale value", item, err)
+		}
+		if !item.IsExpired(time.Now()) {
+			t.Fatal("stale item is not flagged as expired")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("loader called %d times, want the error window to serve the stale value", n)
+	}
+
+	// without a retained value the loader error is returned
+	if _, err := c.GetOrLoad(context.Background(), "other", time.Minute, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad without a stale value err = %v", err)
+	}
+}
+
+func TestStaleOnErrorOff(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var calls, fail int32
+	loader := countingLoader(&calls, &fail)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "k")
+	atomic.StoreInt32(&fail, 1)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad err = %v, want the loader error", err)
+	}
+}
+
+// rewindLoadRun moves the last loader run for key d into the past
+func rewindLoadRun(c *Cache, key string, d time.Duration) {
+	c.loadMut.Lock()
+	run := c.loadRuns[key]
+	run.at = run.at.Add(-d)
+	c.loadRuns[key] = run
+	c.loadMut.Unlock()
+}
+
+func TestLoaderRateLimit(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithLoaderRateLimit(time.Minute))
+	var calls, fail int32
+	loader := countingLoader(&calls, &fail)
+
+	for i := 0; i < 5; i++ {
+		item, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader)
+		if err != nil || item.Value != "value" {
+			t.Fatalf("GetOrLoad = %v, %v", item, err)
+		}
+		// the first lookup removes the expired item so every later call is a miss
+		// served the last loaded item without calling the loader
+		if i == 0 {
+			expire(c, "k")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("loader called %d times inside one interval", n)
+	}
+
+	rewindLoadRun(c, "k", 2*time.Minute)
+	atomic.StoreInt32(&fail, 1)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad after the interval err = %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("loader called %d times, want a new run after the interval", n)
+	}
+	// the failed run is a miss until the next interval
+	atomic.StoreInt32(&fail, 0)
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetOrLoad after a failed run err = %v, want a miss", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("loader called %d times inside the interval after a failure", n)
+	}
+}
+
+func TestFlushDiscardsInFlightLoad(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan *Item)
+	go func() {
+		item, err := c.GetOrLoad(context.Background(), "k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- item
+	}()
+
+	<-started
+	c.Flush()
+	close(release)
+	// the caller still gets the loaded value, it is just not stored
+	if item := <-done; item == nil || item.Value != "stale" {
+		t.Fatalf("GetOrLoad returned %v", item)
+	}
+	if _, err := c.Get("k"); err == nil {
+		t.Fatal("a load started before Flush stored its value")
+	}
+
+	// a load started after the flush is stored as usual
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "fresh", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := c.Get("k"); err != nil || item.Value != "fresh" {
+		t.Fatalf("Get after a new load = %v, %v", item, err)
+	}
+}
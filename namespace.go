@@ -0,0 +1,83 @@
+package skyndiminni
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Namespace is a view of the cache where every key is prefixed with the namespace name
+// all namespaces share the cache's items, limits and cleanup
+type Namespace struct {
+	c          *Cache
+	prefix     string
+	defaultTTL time.Duration
+}
+
+// Namespace returns a handle that prepends prefix and ":" to every key
+// defaultTTL is used by SetDefault, 0 uses the cache's default expiration
+func (c *Cache) Namespace(prefix string, defaultTTL time.Duration) *Namespace {
+	return &Namespace{
+		c:          c,
+		prefix:     prefix + ":",
+		defaultTTL: defaultTTL,
+	}
+}
+
+// Get gets a non expired value based off provided key in the namespace
+func (ns *Namespace) Get(key string) (*Item, error) {
+	return ns.c.Get(ns.prefix + key)
+}
+
+// Set works like Cache.Set for the key in the namespace
+func (ns *Namespace) Set(key string, value interface{}, expirationTime int64) (*Item, error) {
+	return ns.c.Set(ns.prefix+key, value, expirationTime)
+}
+
+// SetDefault works like Set for the key in the namespace with the namespace's default ttl
+func (ns *Namespace) SetDefault(key string, value interface{}) (*Item, error) {
+	return ns.c.SetWithTTL(ns.prefix+key, value, ns.defaultTTL)
+}
+
+// Delete removes the key in the namespace, returns an error if the key does not exist
+func (ns *Namespace) Delete(key string) error {
+	return ns.c.Delete(ns.prefix + key)
+}
+
+// MoveTo moves key from this namespace to dst keeping its item, expiration included, like Cache.Rename
+// returns an error if the key does not exist or dst belongs to another cache
+func (ns *Namespace) MoveTo(dst *Namespace, key string) error {
+	if dst.c.cache != ns.c.cache {
+		return errors.New("namespaces belong to different caches")
+	}
+	return ns.c.Rename(ns.prefix+key, dst.prefix+key)
+}
+
+// Flush removes every key in the namespace leaving other keys intact
+// like Cache.Flush it keeps loads that started before it from storing their values, in any namespace
+func (ns *Namespace) Flush() {
+	ns.c.deletePrefix(ns.prefix)
+}
+
+// deletePrefix removes every key starting with prefix and returns how many were removed
+// it starts a new epoch so loads already running do not store keys that were just removed
+func (c *cache) deletePrefix(prefix string) int {
+	c.mut.Lock()
+	atomic.AddInt64(&c.epoch, 1)
+	var removed []evictedItem
+	for k := range c.items {
+		if strings.HasPrefix(k, prefix) {
+			removed = append(removed, evictedItem{k, c.remove(k, EvictionDeleted)})
+		}
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+
+	for _, e := range removed {
+		if err := c.publish(e.key); err != nil {
+			c.reportError(err)
+		}
+	}
+	return len(removed)
+}
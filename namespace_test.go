@@ -0,0 +1,125 @@
+package skyndiminni
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespacesDoNotCollide(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	users, orders := c.Namespace("users", 0), c.Namespace("orders", 0)
+	if _, err := users.Set("1", "ann", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orders.Set("1", "book", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := users.Get("1"); err != nil || item.Value != "ann" {
+		t.Fatalf("users.Get = %v, %v", item, err)
+	}
+	if item, err := orders.Get("1"); err != nil || item.Value != "book" {
+		t.Fatalf("orders.Get = %v, %v", item, err)
+	}
+	if item, err := c.Get("users:1"); err != nil || item.Value != "ann" {
+		t.Fatalf("prefixed key Get = %v, %v", item, err)
+	}
+
+	if err := users.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orders.Get("1"); err != nil {
+		t.Fatal("deleting in one namespace removed the other's key")
+	}
+}
+
+func TestNamespaceFlush(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	users, orders := c.Namespace("users", 0), c.Namespace("orders", 0)
+	for _, k := range []string{"1", "2"} {
+		if _, err := users.Set(k, k, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := orders.Set(k, k, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Set("plain", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	users.Flush()
+	if n := c.ItemCount(); n != 3 {
+		t.Fatalf("ItemCount = %d after flushing one namespace", n)
+	}
+	for _, k := range []string{"1", "2"} {
+		if _, err := users.Get(k); err == nil {
+			t.Fatalf("users key %s survived the flush", k)
+		}
+		if _, err := orders.Get(k); err != nil {
+			t.Fatalf("orders key %s was flushed", k)
+		}
+	}
+}
+
+func TestNamespaceDefaultTTL(t *testing.T) {
+	c := newTestCache(t, 2*time.Hour)
+	short, long, inherit := c.Namespace("short", time.Minute), c.Namespace("long", time.Hour), c.Namespace("inherit", 0)
+	for _, ns := range []*Namespace{short, long, inherit} {
+		if _, err := ns.SetDefault("k", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, want := range map[string]time.Duration{"short:k": time.Minute, "long:k": time.Hour, "inherit:k": 2 * time.Hour} {
+		c.mut.RLock()
+		item := c.items[key]
+		got := time.Duration(item.Expiration-item.creationTime) * time.Second
+		c.mut.RUnlock()
+		if got < want-time.Second || got > want+time.Second {
+			t.Errorf("%s lives for %v, want %v", key, got, want)
+		}
+	}
+
+	// a key expires by its namespace default, the other namespace keeps its key
+	backdate(c, "short:k", 2*time.Minute)
+	c.mut.Lock()
+	c.items["short:k"].Expiration -= int64(2 * time.Minute / time.Second)
+	c.mut.Unlock()
+	if _, err := short.Get("k"); err == nil {
+		t.Fatal("short key outlived its namespace default")
+	}
+	if _, err := long.Get("k"); err != nil {
+		t.Fatalf("long key expired: %v", err)
+	}
+}
+
+func TestNamespaceMoveTo(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	pending, done := c.Namespace("pending", time.Hour), c.Namespace("done", time.Minute)
+	if _, err := pending.SetDefault("job", "payload"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := pending.Get("job")
+
+	if err := pending.MoveTo(done, "job"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pending.Get("job"); err == nil {
+		t.Fatal("moved key is still in the source namespace")
+	}
+	item, err := done.Get("job")
+	if err != nil || item.Value != "payload" {
+		t.Fatalf("done.Get = %v, %v", item, err)
+	}
+	// the ttl comes with the item, not from the destination's default
+	if item.Expiration != before.Expiration || item.creationTime != before.creationTime {
+		t.Fatalf("moved item expires %d created %d, want %d and %d", item.Expiration, item.creationTime, before.Expiration, before.creationTime)
+	}
+
+	if err := pending.MoveTo(done, "job"); err == nil {
+		t.Fatal("moving a missing key succeeded")
+	}
+	other := newTestCache(t, NoExpiration).Namespace("done", 0)
+	if err := done.MoveTo(other, "job"); err == nil {
+		t.Fatal("moved a key to a namespace of another cache")
+	}
+}
@@ -0,0 +1,126 @@
+package skyndiminni
+
+import (
+	"container/list"
+	"time"
+)
+
+// WithOrderedKeys makes Keys return keys in insertion order
+// it keeps a linked list of the keys next to the map, which costs an allocation per new key and a little memory
+// overwriting a key keeps its place in the order
+func WithOrderedKeys() Option {
+	return func(c *cache) {
+		c.order = list.New()
+		c.orderElems = make(map[string]*list.Element)
+	}
+}
+
+// Keys returns all the non expired keys, in insertion order with WithOrderedKeys
+func (c *Cache) Keys() []string {
+	if c.snapshotReads {
+		items := live(c.snapshot(), -1)
+		keys := make([]string, len(items))
+		for i, e := range items {
+			keys[i] = e.key
+		}
+		return keys
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.keys(time.Now())
+}
+
+// KeysLimit returns at most n non expired keys
+// which keys are returned is arbitrary unless WithOrderedKeys is used, then they are the oldest n
+func (c *Cache) KeysLimit(n int) []string {
+	if n <= 0 {
+		return []string{}
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	keys := make([]string, 0, n)
+	c.each(time.Now(), func(k string, item *Item) bool {
+		keys = append(keys, k)
+		return len(keys) < n
+	})
+	return keys
+}
+
+// Range calls fn for every non expired item until fn returns false
+// the items are collected under the read lock first so fn can use the cache
+func (c *Cache) Range(fn func(key string, item *Item) bool) {
+	c.RangeLimit(-1, fn)
+}
+
+// RangeLimit works like Range for at most n items, n of less than 0 is no limit
+// which items are visited is arbitrary unless WithOrderedKeys is used, then they are the oldest n
+func (c *Cache) RangeLimit(n int, fn func(key string, item *Item) bool) {
+	if n == 0 {
+		return
+	}
+	var found []evictedItem
+	if c.snapshotReads {
+		found = live(c.snapshot(), n)
+	} else {
+		c.mut.RLock()
+		c.each(time.Now(), func(k string, item *Item) bool {
+			found = append(found, evictedItem{k, item})
+			return n < 0 || len(found) < n
+		})
+		c.mut.RUnlock()
+	}
+
+	for _, e := range found {
+		if !fn(e.key, e.item.view()) {
+			return
+		}
+	}
+}
+
+// keys returns the non expired keys, the caller must hold the lock
+func (c *cache) keys(now time.Time) []string {
+	keys := make([]string, 0, len(c.items))
+	c.each(now, func(k string, item *Item) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// each calls fn for the non expired items until it returns false, in insertion order with WithOrderedKeys
+// the caller must hold the lock
+func (c *cache) each(now time.Time, fn func(k string, item *Item) bool) {
+	if c.order != nil {
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			k := e.Value.(string)
+			if item := c.items[k]; !item.IsExpired(now) && !fn(k, item) {
+				return
+			}
+		}
+		return
+	}
+	for k, item := range c.items {
+		if !item.IsExpired(now) && !fn(k, item) {
+			return
+		}
+	}
+}
+
+// orderKey appends a new key to the insertion order, the caller must hold the write lock
+func (c *cache) orderKey(key string) {
+	if c.order == nil {
+		return
+	}
+	c.orderElems[key] = c.order.PushBack(key)
+}
+
+// unorderKey removes the key from the insertion order, the caller must hold the write lock
+func (c *cache) unorderKey(key string) {
+	if c.order == nil {
+		return
+	}
+	if e := c.orderElems[key]; e != nil {
+		c.order.Remove(e)
+		delete(c.orderElems, key)
+	}
+}
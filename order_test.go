@@ -0,0 +1,96 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrderedKeys(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithOrderedKeys(), WithSetOverwrite(true))
+	for _, k := range []string{"c", "a", "d", "b"} {
+		if _, err := c.Set(k, 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an overwrite keeps its place, a deleted key leaves the order and comes back at the end
+	if _, err := c.Set("a", 2, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("e", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("c", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(c.Keys()); got != "[a b e c]" {
+		t.Fatalf("Keys = %s, want [a b e c]", got)
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if c.order.Len() != 4 || len(c.orderElems) != 4 {
+		t.Fatalf("order list has %d keys, index %d", c.order.Len(), len(c.orderElems))
+	}
+}
+
+func TestKeysLimit(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	for i := 0; i < 10; i++ {
+		if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		expire(c, fmt.Sprint(i))
+	}
+
+	for n, want := range map[int]int{-1: 0, 0: 0, 3: 3, 5: 5, 100: 5} {
+		keys := c.KeysLimit(n)
+		if len(keys) != want {
+			t.Errorf("KeysLimit(%d) returned %d keys, want %d", n, len(keys), want)
+		}
+		for _, k := range keys {
+			if _, err := c.Get(k); err != nil {
+				t.Errorf("KeysLimit(%d) returned %s which is not live", n, k)
+			}
+		}
+	}
+
+	var visited int
+	c.RangeLimit(2, func(key string, item *Item) bool {
+		visited++
+		if item.IsExpired(time.Now()) {
+			t.Errorf("RangeLimit visited expired %s", key)
+		}
+		return true
+	})
+	if visited != 2 {
+		t.Fatalf("RangeLimit(2) visited %d items", visited)
+	}
+	visited = 0
+	c.RangeLimit(-1, func(key string, item *Item) bool {
+		visited++
+		return true
+	})
+	if visited != 5 {
+		t.Fatalf("RangeLimit(-1) visited %d items, want every live item", visited)
+	}
+}
+
+func TestKeysLimitOrdered(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithOrderedKeys())
+	for _, k := range []string{"c", "a", "d", "b"} {
+		if _, err := c.Set(k, 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fmt.Sprint(c.KeysLimit(2)); got != "[c a]" {
+		t.Fatalf("KeysLimit(2) = %s, want the oldest keys [c a]", got)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build otel
+// +build otel
+
+package skyndiminni
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer creates spans with tracer for "GetOrLoad" calls, "loader" runs and "bulk hydration" fetches
+// spans carry the key, if it was a hit and the duration, loader spans are children of the caller's span
+// bulk hydration spans have no parent as one fetch serves many Get calls and Get takes no context
+// only available when built with the otel build tag so the core package has no dependencies
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *cache) {
+		c.spanFn = func(ctx context.Context, op, key string) (context.Context, func(hit bool, err error)) {
+			start := time.Now()
+			ctx, span := tracer.Start(ctx, "skyndiminni."+op)
+			return ctx, func(hit bool, err error) {
+				// bulk hydration fetches many keys at once so there is no single key
+				if key != "" {
+					span.SetAttributes(attribute.String("cache.key", key))
+				}
+				span.SetAttributes(
+					attribute.Bool("cache.hit", hit),
+					attribute.Int64("cache.duration_us", time.Since(start).Microseconds()),
+				)
+				if err != nil {
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+				}
+				span.End()
+			}
+		}
+	}
+}
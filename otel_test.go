@@ -0,0 +1,124 @@
+//go:build otel
+// +build otel
+
+package skyndiminni
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// spanRecorder is a tracer keeping every span it starts in memory
+type spanRecorder struct {
+	noop.Tracer
+	mut   sync.Mutex
+	spans []*recordedSpan
+}
+
+// recordedSpan is a span started by spanRecorder
+type recordedSpan struct {
+	noop.Span
+	name   string
+	parent *recordedSpan
+	mut    sync.Mutex
+	attrs  map[attribute.Key]attribute.Value
+	ended  bool
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent, _ := trace.SpanFromContext(ctx).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[attribute.Key]attribute.Value{}}
+	r.mut.Lock()
+	r.spans = append(r.spans, span)
+	r.mut.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (r *spanRecorder) span(name string) *recordedSpan {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	for _, span := range r.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.mut.Lock()
+	s.ended = true
+	s.mut.Unlock()
+}
+
+func TestTracerLoaderMiss(t *testing.T) {
+	rec := &spanRecorder{}
+	c := newTestCache(t, NoExpiration, WithTracer(rec))
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		return "loaded", nil
+	}
+
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); err != nil {
+		t.Fatal(err)
+	}
+	get, load := rec.span("skyndiminni.GetOrLoad"), rec.span("skyndiminni.loader")
+	if get == nil || load == nil {
+		t.Fatalf("spans = %v", rec.spans)
+	}
+	if load.parent != get {
+		t.Fatal("loader span is not a child of the GetOrLoad span")
+	}
+	for _, span := range []*recordedSpan{get, load} {
+		if !span.ended || span.attrs["cache.key"].AsString() != "k" || span.attrs["cache.hit"].AsBool() {
+			t.Fatalf("%s: ended = %v, attrs = %v", span.name, span.ended, span.attrs)
+		}
+		if _, ok := span.attrs["cache.duration_us"]; !ok {
+			t.Fatalf("%s has no duration", span.name)
+		}
+	}
+
+	// a hit does not run the loader
+	rec.spans = nil
+	if _, err := c.GetOrLoad(context.Background(), "k", time.Minute, loader); err != nil {
+		t.Fatal(err)
+	}
+	if rec.span("skyndiminni.loader") != nil || !rec.span("skyndiminni.GetOrLoad").attrs["cache.hit"].AsBool() {
+		t.Fatalf("hit spans = %v", rec.spans)
+	}
+}
+
+func TestTracerBulkHydration(t *testing.T) {
+	rec := &spanRecorder{}
+	c := newTestCache(t, NoExpiration, WithTracer(rec), WithBulkHydration(func(keys []string) (map[string]interface{}, error) {
+		values := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			values[k] = k
+		}
+		return values, nil
+	}))
+
+	if _, err := c.Get("k"); err != nil {
+		t.Fatal(err)
+	}
+	fetch := rec.span("skyndiminni.bulk hydration")
+	if fetch == nil {
+		t.Fatalf("spans = %v", rec.spans)
+	}
+	if fetch.parent != nil || !fetch.ended {
+		t.Fatalf("bulk hydration span: parent = %v, ended = %v", fetch.parent, fetch.ended)
+	}
+}
@@ -0,0 +1,171 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Codec encodes and decodes the snapshots written by Save and read by Load
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// GobCodec is the default Codec, values keep their types but must be registered with gob.Register
+type GobCodec struct{}
+
+// Encode writes v to w with encoding/gob
+func (GobCodec) Encode(w io.Writer, v interface{}) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// Decode reads v from r with encoding/gob
+func (GobCodec) Decode(r io.Reader, v interface{}) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+// JSONCodec is a Codec for snapshots that other programs can read
+// values are loaded back as the types encoding/json decodes into an interface{}, numbers become float64
+type JSONCodec struct{}
+
+// Encode writes v to w with encoding/json
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode reads v from r with encoding/json
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// WithCodec sets the Codec used by Save and Load, GobCodec by default
+func WithCodec(codec Codec) Option {
+	return func(c *cache) {
+		c.codec = codec
+	}
+}
+
+// snapshotVersion is the version of the snapshots written by Save
+// version 1 snapshots were the map of items without a version header
+const snapshotVersion = 2
+
+var (
+	migrationsMut sync.RWMutex
+	migrations    = make(map[int]migration)
+)
+
+// migration upgrades the items of a snapshot from one version to a later one
+type migration struct {
+	to int
+	fn func(items map[string]*Item) error
+}
+
+// RegisterMigration registers fn to upgrade the items loaded from a version from snapshot to version to
+// Load runs the migrations in order until the items are at the current version
+// a version with no migration from it is upgraded to the next one unchanged
+// it panics if to is not after from or a migration from from is already registered
+func RegisterMigration(from, to int, fn func(items map[string]*Item) error) {
+	if to <= from {
+		panic(fmt.Sprintf("skyndiminni: migration from version %d to %d does not move forward", from, to))
+	}
+	migrationsMut.Lock()
+	defer migrationsMut.Unlock()
+	if _, ok := migrations[from]; ok {
+		panic(fmt.Sprintf("skyndiminni: migration from version %d registered twice", from))
+	}
+	migrations[from] = migration{to: to, fn: fn}
+}
+
+// migrate upgrades items from version to the current snapshot version
+func migrate(version int, items map[string]*Item) error {
+	migrationsMut.RLock()
+	defer migrationsMut.RUnlock()
+	for version < snapshotVersion {
+		m, ok := migrations[version]
+		if !ok {
+			version++
+			continue
+		}
+		if err := m.fn(items); err != nil {
+			return fmt.Errorf("migrating snapshot from version %d to %d: %w", version, m.to, err)
+		}
+		version = m.to
+	}
+	return nil
+}
+
+// snapshot is what Save writes, the items with the version of their layout
+type snapshot struct {
+	Version int
+	Items   map[string]savedItem
+}
+
+// savedItem is an item as it is written by Save
+type savedItem struct {
+	Value      interface{}
+	Expiration int64
+}
+
+// Save writes every non expired item to w with the cache's Codec
+func (c *Cache) Save(w io.Writer) error {
+	c.mut.RLock()
+	now := time.Now()
+	saved := make(map[string]savedItem, len(c.items))
+	for k, item := range c.items {
+		if !item.IsExpired(now) {
+			saved[k] = savedItem{Value: item.view().Value, Expiration: item.Expiration}
+		}
+	}
+	c.mut.RUnlock()
+	return c.codec.Encode(w, snapshot{Version: snapshotVersion, Items: saved})
+}
+
+// Load reads items written by Save from r, upgrades older snapshots with the registered migrations and sets them
+// existing keys are overwritten and items that have expired since they were saved are skipped
+// returns an error for snapshots from a newer version, otherwise the first error setting an item
+func (c *Cache) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var snap snapshot
+	if err := c.codec.Decode(bytes.NewReader(data), &snap); err != nil || snap.Version == 0 {
+		// version 1 snapshots are the bare map of items
+		var legacy map[string]savedItem
+		if lerr := c.codec.Decode(bytes.NewReader(data), &legacy); lerr != nil {
+			if err != nil {
+				return err
+			}
+			return lerr
+		}
+		snap = snapshot{Version: 1, Items: legacy}
+	}
+	if snap.Version > snapshotVersion {
+		return fmt.Errorf("snapshot version %d is newer than the supported version %d", snap.Version, snapshotVersion)
+	}
+
+	items := make(map[string]*Item, len(snap.Items))
+	for k, s := range snap.Items {
+		items[k] = &Item{Value: s.Value, Expiration: s.Expiration}
+	}
+	if err := migrate(snap.Version, items); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var first error
+	for k, item := range items {
+		if item == nil || item.IsExpired(now) {
+			continue
+		}
+		if _, err := c.set(k, item); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
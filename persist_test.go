@@ -0,0 +1,168 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// savedPoint is a struct value stored in persistence tests
+type savedPoint struct {
+	X, Y int
+}
+
+func init() {
+	gob.Register(savedPoint{})
+}
+
+// fillMixed stores values of several types in c and returns them by key, "gone" is stored expired
+func fillMixed(t *testing.T, c *Cache) map[string]interface{} {
+	values := map[string]interface{}{
+		"string": "text",
+		"int":    42,
+		"float":  1.5,
+		"bool":   true,
+		"slice":  []string{"a", "b"},
+		"point":  savedPoint{X: 1, Y: 2},
+	}
+	for k, v := range values {
+		if _, err := c.Set(k, v, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Set("immortal", "forever", 0); err != nil {
+		t.Fatal(err)
+	}
+	values["immortal"] = "forever"
+	if _, err := c.Set("gone", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "gone")
+	return values
+}
+
+func TestSaveLoadGob(t *testing.T) {
+	src := newTestCache(t, NoExpiration)
+	values := fillMixed(t, src)
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestCache(t, NoExpiration)
+	if err := dst.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := dst.ItemCount(); n != len(values) {
+		t.Fatalf("loaded %d items, want %d", n, len(values))
+	}
+	for k, want := range values {
+		item, err := dst.Get(k)
+		if err != nil || !reflect.DeepEqual(item.Value, want) {
+			t.Errorf("Get(%q) = %v, %v, want %#v", k, item, err, want)
+			continue
+		}
+		orig, _ := src.Get(k)
+		if item.Expiration != orig.Expiration {
+			t.Errorf("%s expiration %d, want %d", k, item.Expiration, orig.Expiration)
+		}
+	}
+}
+
+func TestSaveLoadJSON(t *testing.T) {
+	src := newTestCache(t, NoExpiration, WithCodec(JSONCodec{}))
+	fillMixed(t, src)
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestCache(t, NoExpiration, WithCodec(JSONCodec{}))
+	if err := dst.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// json decodes into its own types, numbers as float64, slices as []interface{} and structs as maps
+	want := map[string]interface{}{
+		"string":   "text",
+		"int":      float64(42),
+		"float":    1.5,
+		"bool":     true,
+		"slice":    []interface{}{"a", "b"},
+		"point":    map[string]interface{}{"X": float64(1), "Y": float64(2)},
+		"immortal": "forever",
+	}
+	if n := dst.ItemCount(); n != len(want) {
+		t.Fatalf("loaded %d items, want %d", n, len(want))
+	}
+	for k, v := range want {
+		if item, err := dst.Get(k); err != nil || !reflect.DeepEqual(item.Value, v) {
+			t.Errorf("Get(%q) = %v, %v, want %#v", k, item, err, v)
+		}
+	}
+}
+
+func TestLoadWrongCodec(t *testing.T) {
+	src := newTestCache(t, NoExpiration, WithCodec(JSONCodec{}))
+	if _, err := src.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestCache(t, NoExpiration).Load(&buf); err == nil {
+		t.Fatal("gob Load read a json snapshot")
+	}
+}
+
+// registerV1Migration registers the migration used by the tests once, registering it twice panics
+var registerV1Migration sync.Once
+
+func TestLoadMigratesV1Snapshot(t *testing.T) {
+	registerV1Migration.Do(func() {
+		// version 1 stored names in lower case, the current layout expects them in upper case
+		RegisterMigration(1, 2, func(items map[string]*Item) error {
+			for _, item := range items {
+				if s, ok := item.Value.(string); ok {
+					item.Value = strings.ToUpper(s)
+				}
+			}
+			return nil
+		})
+	})
+
+	// a version 1 snapshot is the bare map of items without a header
+	var buf bytes.Buffer
+	legacy := map[string]savedItem{
+		"name": {Value: "ann", Expiration: inAnHour()},
+		"gone": {Value: "bob", Expiration: anHourAgo()},
+	}
+	if err := gob.NewEncoder(&buf).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestCache(t, NoExpiration)
+	if err := c.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := c.Get("name"); err != nil || item.Value != "ANN" {
+		t.Fatalf("Get(name) = %v, %v, want the migrated value", item, err)
+	}
+	if _, err := c.Get("gone"); err == nil {
+		t.Fatal("expired legacy item was loaded")
+	}
+}
+
+func TestLoadNewerSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (GobCodec{}).Encode(&buf, snapshot{Version: snapshotVersion + 1, Items: map[string]savedItem{}}); err != nil {
+		t.Fatal(err)
+	}
+	err := newTestCache(t, NoExpiration).Load(&buf)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("Load of a newer snapshot err = %v", err)
+	}
+}
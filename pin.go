@@ -0,0 +1,43 @@
+package skyndiminni
+
+import "time"
+
+// Pin keeps the item for key from being evicted to make room or by Evict and WithMaxAge
+// the item still expires and can be deleted, setting the key again stores an unpinned item
+// returns an error if the key does not exist
+func (c *Cache) Pin(key string) error {
+	return c.pin(key, true)
+}
+
+// Unpin lets the item for key be evicted again, returns an error if the key does not exist
+func (c *Cache) Unpin(key string) error {
+	return c.pin(key, false)
+}
+
+// SetPinned works like Set but stores the item pinned, see Pin
+func (c *Cache) SetPinned(key string, value interface{}, expirationTime int64) (*Item, error) {
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+		pinned:     true,
+	}
+	if !c.setOverwrite {
+		return c.add(key, item)
+	}
+	return c.set(key, item)
+}
+
+// pin stores a copy of the item for key with the pinned flag set to pinned
+// readers copy the stored item after releasing the lock so it is not changed in place
+func (c *cache) pin(key string, pinned bool) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	item := c.items[key]
+	if item == nil || item.IsExpired(time.Now()) {
+		return ErrKeyNotFound
+	}
+	next := item.clone()
+	next.pinned = pinned
+	c.insert(key, next)
+	return nil
+}
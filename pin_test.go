@@ -0,0 +1,107 @@
+package skyndiminni
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPinnedSurvivesEviction(t *testing.T) {
+	for _, batch := range []int{1, 3} {
+		t.Run(fmt.Sprintf("batch=%d", batch), func(t *testing.T) {
+			c := newTestCache(t, NoExpiration, WithMaxItems(3), WithEvictionBatch(batch))
+			// an immortal pinned item is the oldest and would be the first victim
+			if _, err := c.SetPinned("config", 1, 0); err != nil {
+				t.Fatal(err)
+			}
+			backdate(c, "config", time.Hour)
+			for i := 0; i < 10; i++ {
+				if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := c.Get("config"); err != nil {
+				t.Fatalf("pinned item was evicted: %v", err)
+			}
+			if _, err := c.Get("9"); err != nil {
+				t.Fatalf("latest item was evicted: %v", err)
+			}
+			if n := c.Len(); n > 3 {
+				t.Fatalf("cache holds %d items over its limit of 3", n)
+			}
+		})
+	}
+}
+
+func TestAllPinnedIsFull(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxItems(2))
+	for _, k := range []string{"a", "b"} {
+		if _, err := c.Set(k, 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Pin(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Set("c", 1, inAnHour()); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("Set with every item pinned err = %v, want ErrCacheFull", err)
+	}
+
+	if err := c.Unpin("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("c", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("a"); err == nil {
+		t.Fatal("unpinned item was not the one evicted")
+	}
+	// a pinned item can still be deleted
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pin("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Pin(missing) err = %v", err)
+	}
+}
+
+func TestPinDoesNotRaceReaders(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithCompression(1))
+	value := strings.Repeat("compressible ", 100)
+	if _, err := c.Set("k", value, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stop, started := make(chan struct{}), make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if item, err := c.Get("k"); err != nil || item.Value != value {
+				t.Errorf("Get = %v, %v", item, err)
+				return
+			}
+		}
+	}()
+	<-started
+	for i := 0; i < 1000; i++ {
+		if err := c.Pin("k"); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Unpin("k"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
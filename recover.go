@@ -0,0 +1,45 @@
+package skyndiminni
+
+import "fmt"
+
+// errorsBuffer is the number of errors Errors can hold before new ones are dropped
+const errorsBuffer = 64
+
+// WithPanicRecovery controls whether panics in user supplied callbacks are recovered
+// recovered panics are sent as errors to Errors, this is on by default
+func WithPanicRecovery(recover bool) Option {
+	return func(c *cache) {
+		c.panicRecovery = recover
+	}
+}
+
+// Errors returns a channel of errors raised in the background, such as recovered callback panics
+// errors are dropped when the channel is full
+func (c *Cache) Errors() <-chan error {
+	return c.errs
+}
+
+// reportError sends err to the errors channel without blocking
+func (c *cache) reportError(err error) {
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+// safeCall runs fn and turns a panic into an error on the errors channel when panic recovery is on
+// returns the error for the recovered panic, nil if fn returned normally
+func (c *cache) safeCall(name string, fn func()) (err error) {
+	if !c.panicRecovery {
+		fn()
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", name, r)
+			c.reportError(err)
+		}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,74 @@
+package skyndiminni
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextError returns the next error on Errors or fails the test
+func nextError(t *testing.T, c *Cache) error {
+	t.Helper()
+	select {
+	case err := <-c.Errors():
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("no error was reported")
+		return nil
+	}
+}
+
+func TestPanickingCallbackRecovered(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	c.OnEvicted(func(key string, value interface{}) { panic("boom") })
+	if _, err := c.Set("k", 1, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	c.initExpiration()
+	if err := nextError(t, c); !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("reported %v", err)
+	}
+
+	if _, err := c.Set("k", 2, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Health(); err != nil {
+		t.Fatalf("cache is not healthy after the panic: %v", err)
+	}
+}
+
+func TestPanickingLoaderRecovered(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	_, err := c.GetOrLoad(context.Background(), "k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		panic("loader boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "loader boom") {
+		t.Fatalf("GetOrLoad err = %v", err)
+	}
+	if err := nextError(t, c); !strings.Contains(err.Error(), "loader boom") {
+		t.Fatalf("reported %v", err)
+	}
+
+	item, err := c.GetOrLoad(context.Background(), "k", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return "loaded", nil
+	})
+	if err != nil || item.Value != "loaded" {
+		t.Fatalf("GetOrLoad after the panic = %v, %v", item, err)
+	}
+}
+
+func TestPanicRecoveryOff(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithPanicRecovery(false))
+	c.OnEvicted(func(key string, value interface{}) { panic("boom") })
+	if _, err := c.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want the callback panic", r)
+		}
+	}()
+	c.Delete("k")
+	t.Fatal("Delete returned after the callback panicked")
+}
@@ -0,0 +1,54 @@
+package skyndiminni
+
+import (
+	"context"
+	"time"
+)
+
+// WithShutdownGrace makes Close wait up to d for running GetOrLoad loaders to finish and deliver their values
+// loaders still running after d have their context canceled, without it Close does not wait for loaders
+func WithShutdownGrace(d time.Duration) Option {
+	return func(c *cache) {
+		c.shutdownGrace = d
+	}
+}
+
+// drainLoads waits up to the shutdown grace for the running loads and then cancels the rest
+func (c *cache) drainLoads() {
+	if c.shutdownGrace <= 0 {
+		return
+	}
+	c.loadMut.Lock()
+	if len(c.loads) == 0 {
+		c.loadMut.Unlock()
+		close(c.stopLoads)
+		return
+	}
+	drained := make(chan struct{})
+	c.drained = drained
+	c.loadMut.Unlock()
+
+	timer := time.NewTimer(c.shutdownGrace)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+	}
+	close(c.stopLoads)
+}
+
+// loadContext returns ctx canceled when Close gives up waiting for loaders, the returned function releases it
+func (c *cache) loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.shutdownGrace <= 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.stopLoads:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,90 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// result is what a GetOrLoad call returned
+type result struct {
+	item *Item
+	err  error
+}
+
+// loadInBackground starts GetOrLoad for key in a goroutine and waits for loader to be running
+func loadInBackground(c *Cache, key string, started chan struct{}, loader func(ctx context.Context, key string) (interface{}, error)) <-chan result {
+	done := make(chan result, 1)
+	go func() {
+		item, err := c.GetOrLoad(context.Background(), key, time.Minute, loader)
+		done <- result{item, err}
+	}()
+	<-started
+	return done
+}
+
+func TestShutdownGraceDeliversLoads(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithShutdownGrace(5*time.Second))
+	started, release := make(chan struct{}), make(chan struct{})
+	done := loadInBackground(c, "k", started, func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a loader was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case r := <-done:
+		if r.err != nil || r.item.Value != "value" {
+			t.Fatalf("waiter got %v, %v, want the loaded value", r.item, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter did not get its value within the grace window")
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after the loader finished")
+	}
+}
+
+func TestShutdownGraceCancelsStragglers(t *testing.T) {
+	const grace = 30 * time.Millisecond
+	c := newTestCache(t, NoExpiration, WithShutdownGrace(grace))
+	started := make(chan struct{})
+	done := loadInBackground(c, "k", started, func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	start := time.Now()
+	c.Close()
+	if d := time.Since(start); d < grace {
+		t.Fatalf("Close returned after %v, before the grace period", d)
+	}
+	select {
+	case r := <-done:
+		if !errors.Is(r.err, context.Canceled) {
+			t.Fatalf("straggler waiter err = %v, want the loader canceled", r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("straggler was not canceled after the grace period")
+	}
+}
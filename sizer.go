@@ -0,0 +1,49 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+)
+
+const (
+	// stringHeader and sliceHeader are the sizes of the string and slice headers on 64-bit platforms
+	stringHeader = 16
+	sliceHeader  = 24
+	// mapEntry is the rough cost of a map slot holding a string key and an item pointer, including its share of bucket overhead
+	mapEntry = 32
+)
+
+// itemSize is the size of the Item struct itself
+var itemSize = int64(reflect.TypeOf(Item{}).Size())
+
+// DefaultSizer estimates the memory used by a value in bytes
+// strings and byte slices count their length plus header, numbers and bools their fixed size
+// other types fall back to the length of their gob encoding and then to the size of their type
+// it is used when WithMaxBytes or WithMaxValueSize is set without WithSizer
+func DefaultSizer(v interface{}) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v)) + stringHeader
+	case []byte:
+		return int64(cap(v)) + sliceHeader
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, uint, int64, uint64, uintptr, float64, complex64:
+		return 8
+	case complex128:
+		return 16
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err == nil {
+		return int64(buf.Len())
+	}
+	return int64(reflect.TypeOf(v).Size())
+}
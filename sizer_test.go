@@ -0,0 +1,50 @@
+package skyndiminni
+
+import "testing"
+
+func TestDefaultSizer(t *testing.T) {
+	type record struct {
+		Name  string
+		Tags  []string
+		Count int
+	}
+	tests := []struct {
+		name     string
+		value    interface{}
+		min, max int64
+	}{
+		{name: "nil", value: nil, min: 0, max: 0},
+		{name: "empty string", value: "", min: stringHeader, max: stringHeader},
+		{name: "string", value: "hello", min: 5 + stringHeader, max: 5 + stringHeader},
+		{name: "bytes", value: make([]byte, 100), min: 100 + sliceHeader, max: 100 + sliceHeader},
+		{name: "int", value: 42, min: 8, max: 8},
+		{name: "int32", value: int32(42), min: 4, max: 4},
+		{name: "bool", value: true, min: 1, max: 1},
+		{name: "float64", value: 1.5, min: 8, max: 8},
+		{name: "int slice", value: []int{1, 2, 3}, min: 3, max: 64},
+		{name: "struct", value: record{Name: "ann", Tags: []string{"a", "b"}, Count: 3}, min: 10, max: 256},
+		// channels cannot be gob encoded so the size of the type is used
+		{name: "unencodable", value: make(chan int), min: 1, max: 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultSizer(tt.value)
+			if got < 0 {
+				t.Fatalf("DefaultSizer = %d", got)
+			}
+			if got < tt.min || got > tt.max {
+				t.Fatalf("DefaultSizer = %d, want between %d and %d", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestMaxBytesDefaultsSizer(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxBytes(1000))
+	if _, err := c.Set("k", "hello", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Stats().Bytes; got != DefaultSizer("hello") {
+		t.Fatalf("Bytes = %d, want the DefaultSizer estimate", got)
+	}
+}
@@ -1,8 +1,11 @@
 package skyndiminni
 
 import (
+	"container/list"
+	"crypto/cipher"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,11 +15,92 @@ type Cache struct {
 }
 
 type cache struct {
+	// the atomic counters are kept first so they are 64-bit aligned
+	count                int64
+	hits                 int64
+	misses               int64
+	epoch                int64
+	cleanupRunning       int32
 	defaultExpr          time.Duration
 	checkExpiredInterval time.Duration
+	cleanupMin           time.Duration
+	cleanupMax           time.Duration
+	maxAge               time.Duration
+	initialCapacity      int
+	setOverwrite         bool
+	renameOverwrite      bool
+	snapshotReads        bool
+	setCooldown          time.Duration
+	hasher               func(value interface{}) string
+	interned             map[string]*internedValue
+	maxItems             int
+	evictBatch           int
+	rejectOnFull         bool
+	sizer                func(value interface{}) int64
+	maxValueSize         int64
+	maxBytes             int64
+	bytes                int64
+	alertHigh            float64
+	alertFn              func(ratio float64)
+	alertAbove           bool
+	alertMut             sync.Mutex
+	alertSendMut         sync.Mutex
+	alerts               []float64
+	errorTTL             time.Duration
+	loadMut              sync.Mutex
+	loads                map[string]*loadCall
+	loadErrs             map[string]loadError
+	loadInterval         time.Duration
+	loadRuns             map[string]loadRun
+	staleOnError         bool
+	loadSlots            chan struct{}
+	shutdownGrace        time.Duration
+	drained              chan struct{}
+	stopLoads            chan struct{}
+	loadLatency          *latencyRing
+	waiters              map[string]*keyWaiters
+	maxWaiters           int
+	watchers             map[*watcher]struct{}
+	debounceMut          sync.Mutex
+	debounces            map[string]*debounced
+	indexes              map[string]*valueIndex
+	history              []EvictionRecord
+	historyNext          int
+	historyFull          bool
+	hydrator             func(keys []string) (map[string]interface{}, error)
+	hydrateMut           sync.Mutex
+	hydration            *hydrationBatch
+	keyLocks             keyLocks
+	breaker              *breaker
+	slowOpThreshold      time.Duration
+	slowOpFn             func(op string, dur time.Duration)
+	spanFn               spanFunc
+	costTTL              func(cost int64, baseTTL time.Duration) time.Duration
+	compressMin          int
+	encryptKey           []byte
+	aead                 cipher.AEAD
+	rawBytes             int64
+	bucketSize           time.Duration
+	buckets              map[int64]map[string]struct{}
+	order                *list.List
+	orderElems           map[string]*list.Element
 	items                map[string]*Item
+	onEvicted            func(key string, value interface{})
+	broadcaster          Broadcaster
+	codec                Codec
+	panicRecovery        bool
+	errs                 chan error
+	evictWorkers         int
+	evictQueueSize       int
+	dropEvictions        bool
+	evictQueue           chan evictedItem
+	evictStopped         bool
+	evictMut             sync.RWMutex
+	evictWg              sync.WaitGroup
 	mut                  sync.RWMutex
 	wg                   *sync.WaitGroup
+	done                 chan struct{}
+	closeOnce            sync.Once
 }
 
 // item is the value of each key value pair
@@ -24,6 +108,20 @@ type Item struct {
 	Value        interface{}
 	Expiration   int64
 	creationTime int64
+	onExpire     func(key string, value interface{})
+	interned     *internedValue
+	size         int64
+	rawSize      int64
+	sized        bool
+	pinned       bool
+	bucket       int64
+	inBucket     bool
+}
+
+// evictedItem is a removed key/value pair waiting for its eviction callbacks
+type evictedItem struct {
+	key  string
+	item *Item
 }
 
 const (
@@ -35,106 +133,889 @@ const (
 	CheckExpired time.Duration = 10 * time.Minute
 )
 
+// Option configures optional behavior of the cache when passed to NewCache
+type Option func(*cache)
+
+// WithMaxAge caps how long any item can live regardless of its expiration
+// the cleanup pass evicts items created more than d ago, including NoExpiration items
+func WithMaxAge(d time.Duration) Option {
+	return func(c *cache) {
+		c.maxAge = d
+	}
+}
+
+// WithInitialCapacity sizes the item map for n keys up front so a burst of inserts does not have to grow it
+func WithInitialCapacity(n int) Option {
+	return func(c *cache) {
+		c.initialCapacity = n
+	}
+}
+
+// WithSetOverwrite makes Set overwrite existing keys instead of returning an error
+// Add keeps the strict behavior
+func WithSetOverwrite(overwrite bool) Option {
+	return func(c *cache) {
+		c.setOverwrite = overwrite
+	}
+}
+
+// WithRenameOverwrite makes Rename replace an existing destination key instead of returning an error
+func WithRenameOverwrite(overwrite bool) Option {
+	return func(c *cache) {
+		c.renameOverwrite = overwrite
+	}
+}
+
 // NewCache takes default time as time.Duration for default expiration time
+// and any number of options
 // creates the Cahe intance
 // starts a goroutine to periodically check to expired keys
 // returns the Cache pointer and an error
-func NewCache(defaultExpiration time.Duration) (*Cache, error) {
+func NewCache(defaultExpiration time.Duration, opts ...Option) (*Cache, error) {
 	c := &cache{
 		defaultExpr:          defaultExpiration,
-		items:                make(map[string]*Item),
+		loads:                make(map[string]*loadCall),
+		loadErrs:             make(map[string]loadError),
+		loadRuns:             make(map[string]loadRun),
+		loadLatency:          new(latencyRing),
+		waiters:              make(map[string]*keyWaiters),
+		buckets:              make(map[int64]map[string]struct{}),
+		bucketSize:           DefaultBucketSize,
 		wg:                   new(sync.WaitGroup),
+		done:                 make(chan struct{}),
+		stopLoads:            make(chan struct{}),
+		errs:                 make(chan error, errorsBuffer),
 		checkExpiredInterval: CheckExpired,
+		panicRecovery:        true,
+		codec:                GobCodec{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.items = make(map[string]*Item, c.initialCapacity)
+
+	if (c.maxBytes > 0 || c.maxValueSize > 0 || c.compressMin > 0) && c.sizer == nil {
+		c.sizer = DefaultSizer
+	}
+
+	if c.encryptKey != nil {
+		aead, err := newAEAD(c.encryptKey)
+		if err != nil {
+			return nil, err
+		}
+		c.aead = aead
+	}
+
+	if c.evictWorkers < 0 || c.evictQueueSize < 0 {
+		return nil, errors.New("async evictions need a positive number of workers and queue size")
+	}
+
+	if c.broadcaster != nil {
+		if err := c.subscribe(); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.evictWorkers > 0 {
+		c.startEvictionWorkers()
 	}
 
 	c.wg.Add(1)
+	atomic.StoreInt32(&c.cleanupRunning, 1)
 	go func() {
 		defer c.wg.Done()
+		defer atomic.StoreInt32(&c.cleanupRunning, 0)
+		interval := c.checkExpiredInterval
 		for {
-			c.initExpiration()
-			time.Sleep(c.checkExpiredInterval)
+			interval = c.nextCleanup(interval, c.initExpiration())
+			select {
+			case <-time.After(interval):
+			case <-c.done:
+				return
+			}
 		}
 	}()
 
 	return &Cache{c}, nil
 }
 
-// Close cleans up the goroutines running
+// Close stops and cleans up the goroutines running
+// with WithShutdownGrace it first waits for running loaders
 func (c *Cache) Close() {
-	c.wg.Wait()
+	c.closeOnce.Do(func() {
+		c.drainLoads()
+		close(c.done)
+		c.stopDebounced()
+		c.wg.Wait()
+		if c.evictQueue != nil {
+			c.stopEvictionWorkers()
+		}
+	})
 }
 
 // Get gets a non expired value based off provided key
+// returns ErrKeyNotFound if the key does not exist and ErrKeyExpired if it has expired but was not cleaned up yet
+// with WithBulkHydration a miss waits for the next bulk fetch and returns the fetched value
 func (c *Cache) Get(key string) (*Item, error) {
+	return c.lookup(key)
+}
+
+// lookup is Get for the unexported cache
+func (c *cache) lookup(key string) (*Item, error) {
+	item, err := c.get(key)
+	if err != nil && c.hydrator != nil {
+		return c.hydrate(key)
+	}
+	return item, err
+}
+
+// get gets a non expired value based off provided key, removing it if it has expired
+// returns ErrKeyNotFound if the key is not stored and ErrKeyExpired if it was but has expired
+func (c *cache) get(key string) (*Item, error) {
 	c.mut.RLock()
 	item := c.items[key]
 	if item == nil {
 		c.mut.RUnlock()
-		return nil, errors.New("key does not exist")
+		atomic.AddInt64(&c.misses, 1)
+		return nil, ErrKeyNotFound
 	}
-	if item.Expiration >= time.Now().Unix() {
-		delete(c.items, key)
+	if item.IsExpired(time.Now()) {
 		c.mut.RUnlock()
-		return nil, errors.New("key does not exist")
+		atomic.AddInt64(&c.misses, 1)
+		c.mut.Lock()
+		var removed []evictedItem
+		if c.items[key] == item {
+			removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+		}
+		c.mut.Unlock()
+		c.evicted(removed)
+		return nil, ErrKeyExpired
 	}
 	c.mut.RUnlock()
+	atomic.AddInt64(&c.hits, 1)
+	return item.view(), nil
+}
+
+// GetIf gets a non expired value based off provided key only if pred accepts the item
+// a rejected item is counted and returned as a miss but stays in the cache
+func (c *Cache) GetIf(key string, pred func(item *Item) bool) (*Item, error) {
+	item, err := c.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !pred(item) {
+		atomic.AddInt64(&c.hits, -1)
+		atomic.AddInt64(&c.misses, 1)
+		return nil, ErrKeyNotFound
+	}
 	return item, nil
 }
 
+// TTL returns the remaining time before the key expires without returning the item
+// returns NoExpiration for items that never expire and an error if the key does not exist
+func (c *Cache) TTL(key string) (time.Duration, error) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	now := time.Now()
+	item := c.items[key]
+	if item == nil || item.IsExpired(now) {
+		return 0, ErrKeyNotFound
+	}
+	if item.Expiration <= 0 {
+		return NoExpiration, nil
+	}
+	// expiration has second resolution so the item stays live for part of the last second
+	if ttl := time.Unix(item.Expiration, 0).Sub(now); ttl > 0 {
+		return ttl, nil
+	}
+	return 0, nil
+}
+
+// ExtendTTL moves the expiration of key by delta, which may be negative, and returns the new expiration
+// an expiration moved into the past expires the item straight away
+// returns an error if the key does not exist or never expires
+func (c *Cache) ExtendTTL(key string, delta time.Duration) (time.Time, error) {
+	c.mut.Lock()
+	now := time.Now()
+	item := c.items[key]
+	if item == nil || item.IsExpired(now) {
+		c.mut.Unlock()
+		return time.Time{}, ErrKeyNotFound
+	}
+	if item.Expiration <= 0 {
+		c.mut.Unlock()
+		return time.Time{}, errors.New("key does not expire")
+	}
+
+	next := item.clone()
+	next.Expiration = item.ExpiresAt().Add(delta).Unix()
+	var removed []evictedItem
+	if next.IsExpired(now) {
+		removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+	} else {
+		c.insert(key, next)
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+	return next.ExpiresAt(), c.publish(key)
+}
+
+// HasMany reports for each of the provided keys whether it exists and has not expired
+func (c *Cache) HasMany(keys []string) map[string]bool {
+	c.mut.RLock()
+	now := time.Now()
+	found := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		item := c.items[k]
+		found[k] = item != nil && !item.IsExpired(now)
+	}
+	c.mut.RUnlock()
+	return found
+}
+
+// Filter returns the non expired items for which pred returns true
+// pred is called while holding the read lock so it must not call back into the cache
+func (c *Cache) Filter(pred func(key string, item *Item) bool) map[string]*Item {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	now := time.Now()
+	found := make(map[string]*Item)
+	for k, item := range c.items {
+		if item.IsExpired(now) {
+			continue
+		}
+		if v := item.view(); pred(k, v) {
+			found[k] = v
+		}
+	}
+	return found
+}
+
+// ItemCount returns the exact number of non expired items in the cache
+// it holds the read lock while scanning unless WithSnapshotReads is used, prefer ApproxItemCount for frequent calls
+func (c *Cache) ItemCount() int {
+	if c.snapshotReads {
+		return len(live(c.snapshot(), -1))
+	}
+	c.mut.RLock()
+	now := time.Now()
+	n := 0
+	for _, item := range c.items {
+		if !item.IsExpired(now) {
+			n++
+		}
+	}
+	c.mut.RUnlock()
+	return n
+}
+
+// Len returns the number of entries in the map under the read lock, including expired items not yet cleaned up
+// unlike ItemCount it does not scan, unlike ApproxItemCount it is exact for the map at the time of the call
+func (c *Cache) Len() int {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return len(c.items)
+}
+
+// ApproxItemCount returns the number of items without taking the lock
+// the count is maintained on insert, delete and expiration so it may include expired items not yet cleaned up
+func (c *Cache) ApproxItemCount() int {
+	return int(atomic.LoadInt64(&c.count))
+}
+
 // Set takes the provided key and checks to make sure it does not exist then creates a new key/value pair with expiration time
+// with WithSetOverwrite(true) an existing key is overwritten and no error is returned
 func (c *Cache) Set(key string, value interface{}, expirationTime int64) (*Item, error) {
+	if !c.setOverwrite {
+		return c.Add(key, value, expirationTime)
+	}
+
+	return c.set(key, &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	})
+}
+
+// SetWithTTL works like Set but takes a ttl instead of an expiration time
+// a ttl of 0 uses the default expiration, NoExpiration never expires and any other negative ttl is an error
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) (*Item, error) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return nil, err
+	}
+	return c.Set(key, value, expirationTime)
+}
+
+// Add creates a new key/value pair with expiration time only if the key does not exist or has expired
+// otherwise returns the existing item and an error
+func (c *Cache) Add(key string, value interface{}, expirationTime int64) (*Item, error) {
+	return c.add(key, &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	})
+}
+
+// SetWithCallback works like Set but takes a ttl and an onExpire callback
+// onExpire is called outside the lock when this item expires or is deleted, in addition to OnEvicted
+func (c *Cache) SetWithCallback(key string, value interface{}, ttl time.Duration, onExpire func(key string, value interface{})) (*Item, error) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return nil, err
+	}
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+		onExpire:   onExpire,
+	}
+	if c.setOverwrite {
+		return c.set(key, item)
+	}
+	return c.add(key, item)
+}
+
+// ClaimOnce claims key for ttl, returns true only for the first caller until the claim expires
+// the claim is an Add of a nil value, false is also returned if the claim could not be stored
+func (c *Cache) ClaimOnce(key string, ttl time.Duration) bool {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return false
+	}
+	_, err = c.add(key, &Item{Expiration: expirationTime})
+	return err == nil
+}
+
+// LoadOrStore returns the non expired item for key with loaded true, otherwise it stores value for ttl and returns it
+// like sync.Map both happen under one write lock, actual is nil if the ttl is invalid or the value could not be stored
+func (c *Cache) LoadOrStore(key string, value interface{}, ttl time.Duration) (actual *Item, loaded bool) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		return nil, false
+	}
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	}
+	if err := c.measure(item); err != nil {
+		return nil, false
+	}
+
 	c.mut.Lock()
-	item := c.items[key]
-	if item != nil {
+	now := time.Now()
+	var removed []evictedItem
+	if existing := c.items[key]; existing != nil {
+		if !existing.IsExpired(now) {
+			c.mut.Unlock()
+			return existing.view(), true
+		}
+		removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+	}
+	item.creationTime = now.Unix()
+	evicted, err := c.store(key, item, now)
+	removed = append(removed, evicted...)
+	c.mut.Unlock()
+	c.evicted(removed)
+	if err != nil {
+		return nil, false
+	}
+	if err := c.publish(key); err != nil {
+		c.reportError(err)
+	}
+	return item.view(), false
+}
+
+// Swap stores value under key for ttl and returns the item it replaced under the same write lock
+// existed is false if the key did not exist or had expired
+// if the value cannot be stored the error is reported on Errors and nil and false are returned
+func (c *Cache) Swap(key string, value interface{}, ttl time.Duration) (previous *Item, existed bool) {
+	expirationTime, err := c.expiration(ttl)
+	if err != nil {
+		c.reportError(err)
+		return nil, false
+	}
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	}
+	if err := c.measure(item); err != nil {
+		c.reportError(err)
+		return nil, false
+	}
+
+	c.mut.Lock()
+	now := time.Now()
+	var removed []evictedItem
+	if existing := c.items[key]; existing != nil {
+		if existing.IsExpired(now) {
+			removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
+		} else {
+			previous = existing
+		}
+	}
+	item.creationTime = now.Unix()
+	evicted, err := c.store(key, item, now)
+	removed = append(removed, evicted...)
+	c.mut.Unlock()
+	c.evicted(removed)
+	if err != nil {
+		c.reportError(err)
+		return nil, false
+	}
+	if err := c.publish(key); err != nil {
+		c.reportError(err)
+	}
+	if previous == nil {
+		return nil, false
+	}
+	return previous.view(), true
+}
+
+// Entry is a key/value pair and its ttl for SetBatch
+type Entry struct {
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+// SetBatch stores every entry with its own ttl under one write lock, overwriting existing keys
+// nothing is stored if any ttl or value is invalid, otherwise the first error storing an entry is returned
+func (c *Cache) SetBatch(entries []Entry) error {
+	items := make([]*Item, len(entries))
+	for i, e := range entries {
+		expirationTime, err := c.expiration(e.TTL)
+		if err != nil {
+			return err
+		}
+		items[i] = &Item{
+			Value:      e.Value,
+			Expiration: expirationTime,
+		}
+		if err := c.measure(items[i]); err != nil {
+			return err
+		}
+	}
+
+	c.mut.Lock()
+	now := time.Now()
+	var removed []evictedItem
+	var first error
+	stored := make([]string, 0, len(entries))
+	for i, e := range entries {
+		items[i].creationTime = now.Unix()
+		evicted, err := c.store(e.Key, items[i], now)
+		removed = append(removed, evicted...)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		stored = append(stored, e.Key)
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+
+	for _, k := range stored {
+		if err := c.publish(k); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Flush removes every key
+// loads and bulk fetches that started before the flush return their values without storing them
+func (c *Cache) Flush() {
+	c.deletePrefix("")
+}
+
+// Delete removes the key/value pair, returns an error if the key does not exist
+func (c *Cache) Delete(key string) error {
+	c.mut.Lock()
+	item := c.remove(key, EvictionDeleted)
+	c.mut.Unlock()
+	if item == nil {
+		return ErrKeyNotFound
+	}
+	c.evicted([]evictedItem{{key, item}})
+	return c.publish(key)
+}
+
+// GetAndDeleteMany removes the listed keys and returns the non expired items among them
+// every key is read and removed under the same lock so each item is only returned to one caller
+func (c *Cache) GetAndDeleteMany(keys []string) map[string]*Item {
+	c.mut.Lock()
+	now := time.Now()
+	found := make(map[string]*Item, len(keys))
+	var removed []evictedItem
+	for _, k := range keys {
+		item := c.remove(k, EvictionDeleted)
+		if item == nil {
+			continue
+		}
+		if !item.IsExpired(now) {
+			found[k] = item.view()
+		}
+		removed = append(removed, evictedItem{k, item})
+	}
+	c.mut.Unlock()
+	c.evicted(removed)
+
+	for _, e := range removed {
+		if err := c.publish(e.key); err != nil {
+			c.reportError(err)
+		}
+	}
+	return found
+}
+
+// Rename moves the item from oldKey to newKey keeping its expiration and creation time
+// returns an error if oldKey does not exist or newKey already exists, unless WithRenameOverwrite(true) is set
+func (c *Cache) Rename(oldKey, newKey string) error {
+	c.mut.Lock()
+	now := time.Now()
+	var removed []evictedItem
+	item := c.items[oldKey]
+	if item == nil || item.IsExpired(now) {
+		if item != nil {
+			removed = append(removed, evictedItem{oldKey, c.remove(oldKey, EvictionExpired)})
+		}
+		c.mut.Unlock()
+		c.evicted(removed)
+		return ErrKeyNotFound
+	}
+	if oldKey == newKey {
 		c.mut.Unlock()
-		return item, errors.New("key already exists")
+		return nil
+	}
+	if dst := c.items[newKey]; dst != nil {
+		if !dst.IsExpired(now) && !c.renameOverwrite {
+			c.mut.Unlock()
+			return errors.New("key already exists")
+		}
+		reason := EvictionReplaced
+		if dst.IsExpired(now) {
+			reason = EvictionExpired
+		}
+		removed = append(removed, evictedItem{newKey, c.remove(newKey, reason)})
 	}
+	// the item moves to the new key so this is not an eviction
+	// a copy is stored as readers may still hold the item and insert interns the value again
+	c.remove(oldKey, "")
+	c.insert(newKey, item.clone())
+	c.mut.Unlock()
+	c.evicted(removed)
 
-	item = &Item{
-		Value:        value,
-		Expiration:   expirationTime,
-		creationTime: time.Now().Unix(),
+	if err := c.publish(oldKey); err != nil {
+		return err
 	}
-	c.items[key] = item
+	return c.publish(newKey)
+}
+
+// OnEvicted sets a function that is called with the key/value pair whenever an item expires or is deleted
+// the function is called outside the lock, pass nil to remove it
+func (c *Cache) OnEvicted(fn func(key string, value interface{})) {
+	c.mut.Lock()
+	c.onEvicted = fn
 	c.mut.Unlock()
-	return item, nil
 }
 
 // Update takes a key/value pair with expiration time and updates existing key
 // if setIfNotExist is true will create new key/value if not exists
 // if setIfNotExist is false then will return an error that key already exists
 func (c *Cache) Update(key string, value interface{}, expirationTime int64, setIfNotExist bool) (*Item, error) {
+	item := &Item{
+		Value:      value,
+		Expiration: expirationTime,
+	}
+	if err := c.measure(item); err != nil {
+		return nil, err
+	}
+
 	c.mut.Lock()
-	item := c.items[key]
+	if !setIfNotExist && c.items[key] == nil {
+		c.mut.Unlock()
+		return nil, ErrKeyNotFound
+	}
 
-	if setIfNotExist {
-		item = &Item{
-			Value:        value,
-			Expiration:   expirationTime,
-			creationTime: time.Now().Unix(),
-		}
-	} else {
-		if item == nil {
-			return nil, errors.New("key does not exist")
-		}
-		item = &Item{
-			Value:        value,
-			Expiration:   expirationTime,
-			creationTime: time.Now().Unix(),
+	now := time.Now()
+	item.creationTime = now.Unix()
+	removed, err := c.store(key, item, now)
+	c.mut.Unlock()
+	c.evicted(removed)
+	if err != nil {
+		return nil, err
+	}
+	return item.view(), c.publish(key)
+}
+
+// UpdateFunc replaces the value of key with the value returned by fn, fn gets the current value and if it exists
+// an existing key keeps its expiration, a new key gets the default expiration
+// fn runs under a lock for the key only so updates to different keys run concurrently
+// UpdateFunc calls for the same key are serialized but other writes to the key are not held off while fn runs
+func (c *Cache) UpdateFunc(key string, fn func(value interface{}, exists bool) (interface{}, error)) (*Item, error) {
+	mu := c.keyLocks.lock(key)
+	mu.Lock()
+	defer mu.Unlock()
+
+	defaultExpiration, _ := c.expiration(0)
+	next := &Item{Expiration: defaultExpiration}
+	var current interface{}
+	item, err := c.get(key)
+	if err == nil {
+		current = item.Value
+		next.Expiration = item.Expiration
+		next.onExpire = item.onExpire
+	}
+	next.Value, err = fn(current, item != nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.set(key, next)
+}
+
+// set stores the item overwriting any existing key and publishes the key
+func (c *cache) set(key string, item *Item) (*Item, error) {
+	item, err := c.put(key, item, anyEpoch)
+	if err != nil {
+		return nil, err
+	}
+	return item, c.publish(key)
+}
+
+// anyEpoch makes put store the item whatever flushes happened before
+const anyEpoch = -1
+
+// put stores the item overwriting any existing key without publishing it
+// unless epoch is anyEpoch the item is returned without being stored if the cache was flushed since epoch
+func (c *cache) put(key string, item *Item, epoch int64) (*Item, error) {
+	if err := c.measure(item); err != nil {
+		return nil, err
+	}
+
+	c.mut.Lock()
+	if epoch != anyEpoch && atomic.LoadInt64(&c.epoch) != epoch {
+		c.mut.Unlock()
+		return item.view(), nil
+	}
+	now := time.Now()
+	item.creationTime = now.Unix()
+	removed, err := c.store(key, item, now)
+	c.mut.Unlock()
+	c.evicted(removed)
+	if err != nil {
+		return nil, err
+	}
+	return item.view(), nil
+}
+
+// add stores the new item only if the key does not exist or has expired
+// otherwise returns the existing item and an error
+func (c *cache) add(key string, item *Item) (*Item, error) {
+	if err := c.measure(item); err != nil {
+		return nil, err
+	}
+
+	c.mut.Lock()
+	now := time.Now()
+	var removed []evictedItem
+	if existing := c.items[key]; existing != nil {
+		if !existing.IsExpired(now) {
+			c.mut.Unlock()
+			return existing.view(), errors.New("key already exists")
 		}
+		removed = append(removed, evictedItem{key, c.remove(key, EvictionExpired)})
 	}
 
-	c.items[key] = item
+	item.creationTime = now.Unix()
+	evicted, err := c.store(key, item, now)
+	removed = append(removed, evicted...)
 	c.mut.Unlock()
-	return item, nil
+	c.evicted(removed)
+	if err != nil {
+		return nil, err
+	}
+	return item.view(), c.publish(key)
+}
+
+// store makes room for a new key and inserts the item, the caller must hold the write lock
+// the returned evicted items must be passed to evicted after unlocking, even with an error
+// returns ErrTooSoon without storing if the key is inside its set cooldown
+func (c *cache) store(key string, item *Item, now time.Time) ([]evictedItem, error) {
+	if c.tooSoon(key, now) {
+		return nil, ErrTooSoon
+	}
+	removed, err := c.makeRoom(key, item, now)
+	if err != nil {
+		return removed, err
+	}
+	c.insert(key, item)
+	return removed, nil
 }
 
-func (c *cache) initExpiration() {
+// initExpiration removes the expired items and returns the share of items it removed
+func (c *cache) initExpiration() float64 {
+	defer c.endOp("cleanup", c.startOp())
 	c.mut.Lock()
+	now := time.Now()
+	total := len(c.items)
+	var removed []evictedItem
 	for k, v := range c.items {
-		if v.Expiration >= time.Now().Unix() {
-			delete(c.items, k)
+		if v.IsExpired(now) {
+			removed = append(removed, evictedItem{k, c.remove(k, EvictionExpired)})
+		} else if !v.pinned && c.pastMaxAge(v, now) {
+			removed = append(removed, evictedItem{k, c.remove(k, EvictionMaxAge)})
 		}
 	}
 	c.mut.Unlock()
+	c.evicted(removed)
+	c.expireLoads(time.Now())
+	if total == 0 {
+		return 0
+	}
+	return float64(len(removed)) / float64(total)
+}
+
+// evicted runs the eviction callbacks for the removed items and any queued capacity alerts
+// the caller must not hold the lock
+func (c *cache) evicted(removed []evictedItem) {
+	c.capacityAlerts()
+	if len(removed) == 0 {
+		return
+	}
+	if c.evictQueue != nil {
+		removed = c.enqueueEvicted(removed)
+	}
+	for _, e := range removed {
+		c.runEvictionCallbacks(e)
+	}
+}
+
+// runEvictionCallbacks calls the item's onExpire callback and the OnEvicted callback
+func (c *cache) runEvictionCallbacks(e evictedItem) {
+	c.mut.RLock()
+	onEvicted := c.onEvicted
+	c.mut.RUnlock()
+	if e.item.onExpire == nil && onEvicted == nil {
+		return
+	}
+	value := e.item.view().Value
+	if e.item.onExpire != nil {
+		c.safeCall("onExpire callback", func() { e.item.onExpire(e.key, value) })
+	}
+	if onEvicted != nil {
+		c.safeCall("OnEvicted callback", func() { onEvicted(e.key, value) })
+	}
+}
+
+// pastMaxAge reports if the item is older than the configured max age
+func (c *cache) pastMaxAge(item *Item, now time.Time) bool {
+	return c.maxAge > 0 && item.creationTime < now.Add(-c.maxAge).Unix()
+}
+
+// insert stores the item and keeps the item counter in sync, the caller must hold the write lock
+func (c *cache) insert(key string, item *Item) {
+	if old, ok := c.items[key]; ok {
+		c.release(old)
+		c.bytes -= old.size
+		c.rawBytes -= old.rawSize
+		c.unindexBucket(key, old)
+	} else {
+		atomic.AddInt64(&c.count, 1)
+		c.orderKey(key)
+	}
+	c.bytes += item.size
+	c.rawBytes += item.rawSize
+	c.intern(item)
+	c.indexBucket(key, item)
+	c.items[key] = item
+	c.reindexKey(key, item)
+	c.wake(key)
+	c.notify(EventSet, key)
+	c.checkCapacity()
+}
+
+// remove deletes the key and keeps the item counter in sync, the caller must hold the write lock
+// the removal is recorded for WithEvictionHistory with reason unless reason is empty
+// returns the removed item or nil if the key did not exist
+func (c *cache) remove(key string, reason EvictionReason) *Item {
+	item, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	delete(c.items, key)
+	atomic.AddInt64(&c.count, -1)
+	c.bytes -= item.size
+	c.rawBytes -= item.rawSize
+	c.release(item)
+	c.unindexBucket(key, item)
+	c.unorderKey(key)
+	c.unindexKey(key)
+	c.recordEviction(key, reason)
+	c.notify(EventRemove, key)
+	c.checkCapacity()
+	return item
+}
+
+// expiration converts a ttl into the Unix expiration time stored on an Item
+// a ttl of 0 uses the default expiration, NoExpiration never expires and any other negative ttl is an error
+// a default expiration of 0 or less never expires
+func (c *cache) expiration(ttl time.Duration) (int64, error) {
+	switch {
+	case ttl == 0:
+		if c.defaultExpr <= 0 {
+			return int64(NoExpiration), nil
+		}
+		ttl = c.defaultExpr
+	case ttl == NoExpiration:
+		return int64(NoExpiration), nil
+	case ttl < 0:
+		return 0, errors.New("ttl must be positive, 0 for the default or NoExpiration")
+	}
+	return time.Now().Add(ttl).Unix(), nil
+}
+
+// clone copies the item so it can be changed without racing readers of the stored item
+func (item *Item) clone() *Item {
+	next := *item
+	// the copy takes its own reference when it is inserted
+	next.interned = nil
+	return &next
+}
+
+// storedValue is a value kept in another form in the map, see WithCompression and WithEncryption
+type storedValue interface {
+	// value returns the value as it was stored
+	value() interface{}
+	// storedSize returns the length of the stored form
+	storedSize() int64
+}
+
+// view returns the item as callers see it, a copy with the original value if it is kept in another form
+func (item *Item) view() *Item {
+	sv, ok := item.Value.(storedValue)
+	if !ok {
+		return item
+	}
+	next := *item
+	next.Value = sv.value()
+	return &next
+}
+
+// CreatedAt returns the time the item was stored, to the second
+func (item *Item) CreatedAt() time.Time {
+	return time.Unix(item.creationTime, 0)
+}
+
+// ExpiresAt returns the time the item expires, the zero time if it never expires
+func (item *Item) ExpiresAt() time.Time {
+	if item.Expiration <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(item.Expiration, 0)
+}
+
+// IsExpired reports if the item has expired at now, items with an Expiration of 0 or less never expire
+// Expiration has second resolution so an item is live until the end of the second it expires in
+func (item *Item) IsExpired(now time.Time) bool {
+	return item.Expiration > 0 && now.Unix() > item.Expiration
 }
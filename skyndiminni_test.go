@@ -0,0 +1,838 @@
+package skyndiminni
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCache creates a cache with opts that is closed when the test finishes
+func newTestCache(t testing.TB, defaultExpiration time.Duration, opts ...Option) *Cache {
+	t.Helper()
+	c, err := NewCache(defaultExpiration, opts...)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// inAnHour is an expiration time far enough out for items that must not expire during a test
+func inAnHour() int64 {
+	return time.Now().Add(time.Hour).Unix()
+}
+
+// anHourAgo is an expiration time for items that are already expired when they are stored
+func anHourAgo() int64 {
+	return time.Now().Add(-time.Hour).Unix()
+}
+
+func TestApproxItemCountConsistent(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("%d-%d", g, i)
+				if _, err := c.Set(key, i, inAnHour()); err != nil {
+					t.Error(err)
+					return
+				}
+				// overwrites must not count twice
+				if _, err := c.Set(key, i+1, inAnHour()); err != nil {
+					t.Error(err)
+					return
+				}
+				if i%3 == 0 {
+					if err := c.Delete(key); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	want := c.ItemCount()
+	if got := c.ApproxItemCount(); got != want {
+		t.Fatalf("ApproxItemCount = %d, ItemCount = %d", got, want)
+	}
+	if want != 8*(500-167) {
+		t.Fatalf("ItemCount = %d", want)
+	}
+
+	if _, err := c.Set("expired", 1, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ApproxItemCount(); got != want+1 {
+		t.Fatalf("ApproxItemCount before cleanup = %d, want %d", got, want+1)
+	}
+	c.initExpiration()
+	if got := c.ApproxItemCount(); got != want {
+		t.Fatalf("ApproxItemCount after cleanup = %d, want %d", got, want)
+	}
+}
+
+func TestSetOverwrite(t *testing.T) {
+	strict := newTestCache(t, NoExpiration)
+	if _, err := strict.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Set("k", 2, inAnHour()); err == nil {
+		t.Fatal("Set overwrote without WithSetOverwrite")
+	}
+
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	noop := func(key string, value interface{}) {}
+	writes := map[string]func(v int) error{
+		"Set": func(v int) error {
+			_, err := c.Set("k", v, inAnHour())
+			return err
+		},
+		"SetWithTTL": func(v int) error {
+			_, err := c.SetWithTTL("k", v, time.Hour)
+			return err
+		},
+		"SetWithCallback": func(v int) error {
+			_, err := c.SetWithCallback("k", v, time.Hour, noop)
+			return err
+		},
+		"SetPinned": func(v int) error {
+			_, err := c.SetPinned("k", v, inAnHour())
+			return err
+		},
+	}
+	for name, write := range writes {
+		for v := 1; v <= 2; v++ {
+			if err := write(v); err != nil {
+				t.Fatalf("%s(%d): %v", name, v, err)
+			}
+		}
+		if item, err := c.Get("k"); err != nil || item.Value != 2 {
+			t.Fatalf("after %s Get = %v, %v", name, item, err)
+		}
+		if _, err := c.Add("k", 3, inAnHour()); err == nil {
+			t.Fatal("Add overwrote a live key")
+		}
+	}
+}
+
+func TestSwap(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxValueSize(64))
+	if prev, existed := c.Swap("k", 1, time.Hour); existed || prev != nil {
+		t.Fatalf("first Swap = %v, %v", prev, existed)
+	}
+	prev, existed := c.Swap("k", 2, time.Hour)
+	if !existed || prev.Value != 1 {
+		t.Fatalf("second Swap = %v, %v", prev, existed)
+	}
+	if item, _ := c.Get("k"); item.Value != 2 {
+		t.Fatalf("stored value = %v", item.Value)
+	}
+
+	if prev, existed := c.Swap("k", strings.Repeat("x", 100), time.Hour); existed || prev != nil {
+		t.Fatalf("failed Swap = %v, %v", prev, existed)
+	}
+	select {
+	case err := <-c.Errors():
+		if !errors.Is(err, ErrValueTooLarge) {
+			t.Fatalf("reported %v", err)
+		}
+	default:
+		t.Fatal("store failure was not reported")
+	}
+	if item, _ := c.Get("k"); item.Value != 2 {
+		t.Fatalf("stored value after failed Swap = %v", item.Value)
+	}
+}
+
+// backdate moves the creation time of key d into the past
+func backdate(c *Cache, key string, d time.Duration) {
+	c.mut.Lock()
+	c.items[key].creationTime -= int64(d / time.Second)
+	c.mut.Unlock()
+}
+
+func TestMaxAge(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithMaxAge(time.Hour))
+	for _, k := range []string{"old ttl", "new ttl"} {
+		if _, err := c.Set(k, 1, time.Now().Add(3*time.Hour).Unix()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range []string{"old immortal", "new immortal"} {
+		if _, err := c.SetWithTTL(k, 1, NoExpiration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backdate(c, "old ttl", 2*time.Hour)
+	backdate(c, "old immortal", 2*time.Hour)
+	backdate(c, "new immortal", 30*time.Minute)
+
+	c.initExpiration()
+	for k, want := range map[string]bool{"old ttl": false, "old immortal": false, "new ttl": true, "new immortal": true} {
+		if _, err := c.Get(k); (err == nil) != want {
+			t.Errorf("Get(%q) err = %v, want present = %v", k, err, want)
+		}
+	}
+}
+
+// expire makes the item for key expired without removing it
+func expire(c *Cache, key string) {
+	c.mut.Lock()
+	c.items[key].Expiration = anHourAgo()
+	c.mut.Unlock()
+}
+
+func TestSetWithCallback(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	fired := map[string][]string{}
+	record := func(name string) func(key string, value interface{}) {
+		return func(key string, value interface{}) {
+			fired[name] = append(fired[name], key)
+		}
+	}
+	c.OnEvicted(record("global"))
+	if _, err := c.SetWithCallback("a", 1, time.Hour, record("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithCallback("b", 2, time.Hour, record("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	expire(c, "a")
+	c.initExpiration()
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{"a": {"a"}, "b": {"b"}, "global": {"a", "b"}}
+	if fmt.Sprint(fired) != fmt.Sprint(want) {
+		t.Fatalf("callbacks fired %v, want %v", fired, want)
+	}
+}
+
+func TestHasMany(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("live", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("expired", 1, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	got := c.HasMany([]string{"live", "expired", "absent"})
+	want := map[string]bool{"live": true, "expired": false, "absent": false}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("HasMany = %v, want %v", got, want)
+	}
+	// HasMany does not remove the expired item
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len = %d after HasMany", n)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetWithTTL("finite", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithTTL("immortal", 1, NoExpiration); err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, err := c.TTL("finite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := time.Minute - ttl; diff < -time.Second || diff > time.Second {
+		t.Fatalf("TTL = %v, want about a minute", ttl)
+	}
+	if ttl, err := c.TTL("immortal"); err != nil || ttl != NoExpiration {
+		t.Fatalf("TTL(immortal) = %v, %v", ttl, err)
+	}
+	if _, err := c.TTL("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("TTL(missing) err = %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var fired []string
+	if _, err := c.SetWithCallback("old", 1, time.Hour, func(key string, value interface{}) { fired = append(fired, key) }); err != nil {
+		t.Fatal(err)
+	}
+	backdate(c, "old", time.Minute)
+	before, _ := c.Get("old")
+
+	if err := c.Rename("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("old"); err == nil {
+		t.Fatal("old key still exists")
+	}
+	after, err := c.Get("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Value != 1 || after.Expiration != before.Expiration || !after.CreatedAt().Equal(before.CreatedAt()) {
+		t.Fatalf("renamed item = %+v, want %+v", after, before)
+	}
+	if len(fired) != 0 {
+		t.Fatalf("rename fired callbacks for %v", fired)
+	}
+	if err := c.Delete("new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fired) != 1 || fired[0] != "new" {
+		t.Fatalf("callback fired for %v, want the new key", fired)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("a", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("b", 2, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("expired", 3, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Rename("missing", "x"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Rename(missing) err = %v", err)
+	}
+	if err := c.Rename("expired", "x"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Rename(expired) err = %v", err)
+	}
+	if err := c.Rename("a", "b"); err == nil {
+		t.Fatal("Rename onto an existing key succeeded")
+	}
+	if item, _ := c.Get("b"); item.Value != 2 {
+		t.Fatalf("destination changed to %v", item.Value)
+	}
+
+	overwrite := newTestCache(t, NoExpiration, WithRenameOverwrite(true))
+	for k, v := range map[string]int{"a": 1, "b": 2} {
+		if _, err := overwrite.Set(k, v, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := overwrite.Rename("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := overwrite.Get("b"); item.Value != 1 {
+		t.Fatalf("destination = %v after overwrite", item.Value)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	for k, v := range map[string]interface{}{"user:1": "ann", "user:2": 2, "order:1": "x"} {
+		if _, err := c.Set(k, v, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Set("user:3", "expired", anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+
+	users := c.Filter(func(key string, item *Item) bool { return strings.HasPrefix(key, "user:") })
+	if len(users) != 2 || users["user:1"] == nil || users["user:2"] == nil {
+		t.Fatalf("Filter by key = %v", users)
+	}
+	strs := c.Filter(func(key string, item *Item) bool {
+		_, ok := item.Value.(string)
+		return ok
+	})
+	if len(strs) != 2 || strs["user:1"] == nil || strs["order:1"] == nil {
+		t.Fatalf("Filter by value type = %v", strs)
+	}
+}
+
+// BenchmarkSetBurst inserts a burst of unique keys into a new cache
+// max-ns/set is the slowest single Set, which is where map growth shows up
+func BenchmarkSetBurst(b *testing.B) {
+	const burst = 100000
+	keys := make([]string, burst)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	for _, bb := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "growing"},
+		{name: "presized", opts: []Option{WithInitialCapacity(burst)}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			var slowest time.Duration
+			exp := inAnHour()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				c := newTestCache(b, NoExpiration, bb.opts...)
+				b.StartTimer()
+				for _, k := range keys {
+					start := time.Now()
+					c.Set(k, 1, exp)
+					if d := time.Since(start); d > slowest {
+						slowest = d
+					}
+				}
+			}
+			b.ReportMetric(float64(slowest.Nanoseconds()), "max-ns/set")
+		})
+	}
+}
+
+func TestUpdateFuncSerializesSameKey(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	increment := func(value interface{}, exists bool) (interface{}, error) {
+		if !exists {
+			return 1, nil
+		}
+		return value.(int) + 1, nil
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, err := c.UpdateFunc("n", increment); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if item, err := c.Get("n"); err != nil || item.Value != 800 {
+		t.Fatalf("Get = %v, %v, want 800", item, err)
+	}
+}
+
+// spin does a fixed amount of work standing in for the read-modify-write of an update
+func spin(n int) int {
+	x := 0
+	for i := 0; i < n; i++ {
+		x = x*31 + i
+	}
+	return x
+}
+
+// BenchmarkUpdateFunc runs parallel read-modify-writes spread over many keys or all on one key
+// WithLock is the same update under the global write lock for comparison
+func BenchmarkUpdateFunc(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	update := func(value interface{}, exists bool) (interface{}, error) {
+		return spin(2000), nil
+	}
+	run := func(b *testing.B, do func(c *Cache, key string)) {
+		c := newTestCache(b, NoExpiration)
+		var next int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := int(atomic.AddInt64(&next, 1)) * 7919
+			for pb.Next() {
+				do(c, keys[i%len(keys)])
+				i++
+			}
+		})
+	}
+	b.Run("spread", func(b *testing.B) {
+		run(b, func(c *Cache, key string) { c.UpdateFunc(key, update) })
+	})
+	b.Run("same key", func(b *testing.B) {
+		run(b, func(c *Cache, key string) { c.UpdateFunc("hot", update) })
+	})
+	b.Run("WithLock spread", func(b *testing.B) {
+		run(b, func(c *Cache, key string) {
+			c.WithLock(func(tx *Tx) {
+				tx.Get(key)
+				tx.Set(key, spin(2000), 0)
+			})
+		})
+	})
+}
+
+func TestSetWithTTLSemantics(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaultTTL time.Duration
+		ttl        time.Duration
+		want       time.Duration
+		wantErr    bool
+	}{
+		{name: "zero uses the default", defaultTTL: time.Hour, ttl: 0, want: time.Hour},
+		{name: "zero without a default never expires", defaultTTL: NoExpiration, ttl: 0, want: NoExpiration},
+		{name: "NoExpiration never expires", defaultTTL: time.Hour, ttl: NoExpiration, want: NoExpiration},
+		{name: "positive ttl", defaultTTL: time.Hour, ttl: time.Minute, want: time.Minute},
+		{name: "other negative ttl", defaultTTL: time.Hour, ttl: -time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(t, tt.defaultTTL)
+			item, err := c.SetWithTTL("k", 1, tt.ttl)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("SetWithTTL accepted the ttl")
+				}
+				if _, err := c.Get("k"); err == nil {
+					t.Fatal("the item was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == NoExpiration {
+				if item.Expiration != int64(NoExpiration) {
+					t.Fatalf("Expiration = %d, want NoExpiration", item.Expiration)
+				}
+				return
+			}
+			if diff := time.Until(item.ExpiresAt()) - tt.want; diff < -time.Second || diff > time.Second {
+				t.Fatalf("expires in %v, want %v", time.Until(item.ExpiresAt()), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAndDeleteManyClaimsOnce(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	const n = 1000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+		if _, err := c.Set(keys[i], i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the two drainers overlap on the middle half of the keys
+	sets := [][]string{keys[:3*n/4], keys[n/4:]}
+	results := make([]map[string]*Item, len(sets))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, set := range sets {
+		wg.Add(1)
+		go func(i int, set []string) {
+			defer wg.Done()
+			<-start
+			results[i] = map[string]*Item{}
+			for j := 0; j < len(set); j += 10 {
+				end := j + 10
+				if end > len(set) {
+					end = len(set)
+				}
+				for k, item := range c.GetAndDeleteMany(set[j:end]) {
+					results[i][k] = item
+				}
+			}
+		}(i, set)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, k := range keys {
+		_, a := results[0][k]
+		_, b := results[1][k]
+		if a == b {
+			t.Fatalf("key %s claimed by both = %v", k, a)
+		}
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("%d keys left", n)
+	}
+}
+
+func TestGetAndDeleteManySkipsExpired(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("live", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("expired", 1, anHourAgo()); err != nil {
+		t.Fatal(err)
+	}
+	got := c.GetAndDeleteMany([]string{"live", "expired", "absent"})
+	if len(got) != 1 || got["live"] == nil {
+		t.Fatalf("GetAndDeleteMany = %v", got)
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("%d keys left", n)
+	}
+}
+
+func TestItemExpiresAt(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	finite := &Item{Expiration: at.Unix()}
+	if got := finite.ExpiresAt(); !got.Equal(at) {
+		t.Fatalf("ExpiresAt = %v, want %v", got, at)
+	}
+	for _, exp := range []int64{0, int64(NoExpiration)} {
+		if got := (&Item{Expiration: exp}).ExpiresAt(); !got.IsZero() {
+			t.Fatalf("ExpiresAt for Expiration %d = %v, want the zero time", exp, got)
+		}
+	}
+}
+
+func TestItemIsExpired(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	item := &Item{Expiration: at.Unix()}
+	tests := []struct {
+		now  time.Time
+		want bool
+	}{
+		{now: at.Add(-time.Second), want: false},
+		{now: at, want: false},
+		{now: at.Add(999 * time.Millisecond), want: false},
+		{now: at.Add(time.Second), want: true},
+		{now: at.Add(time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		if got := item.IsExpired(tt.now); got != tt.want {
+			t.Errorf("IsExpired(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+	immortal := &Item{Expiration: int64(NoExpiration)}
+	if immortal.IsExpired(at.Add(100 * 365 * 24 * time.Hour)) {
+		t.Fatal("an immortal item expired")
+	}
+}
+
+func TestExtendTTL(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetWithTTL("k", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := c.Get("k")
+	start := item.ExpiresAt()
+
+	at, err := c.ExtendTTL("k", 30*time.Minute)
+	if err != nil || !at.Equal(start.Add(30*time.Minute)) {
+		t.Fatalf("extend = %v, %v, want %v", at, err, start.Add(30*time.Minute))
+	}
+	at, err = c.ExtendTTL("k", -time.Hour)
+	if err != nil || !at.Equal(start.Add(-30*time.Minute)) {
+		t.Fatalf("shorten = %v, %v, want %v", at, err, start.Add(-30*time.Minute))
+	}
+	if item, _ := c.Get("k"); !item.ExpiresAt().Equal(at) {
+		t.Fatalf("stored expiration %v, want %v", item.ExpiresAt(), at)
+	}
+	// the item handed out before is not changed
+	if !item.ExpiresAt().Equal(start) {
+		t.Fatal("ExtendTTL changed an item a caller held")
+	}
+
+	if _, err := c.ExtendTTL("k", -2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("k"); err == nil {
+		t.Fatal("shortening past now did not expire the item")
+	}
+	if _, err := c.ExtendTTL("k", time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("ExtendTTL on a missing key err = %v", err)
+	}
+	if _, err := c.SetWithTTL("immortal", 1, NoExpiration); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ExtendTTL("immortal", time.Hour); err == nil {
+		t.Fatal("extended an item that never expires")
+	}
+}
+
+func TestClaimOnce(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var wins int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if c.ClaimOnce("webhook", time.Minute) {
+				atomic.AddInt32(&wins, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if wins != 1 {
+		t.Fatalf("%d callers claimed the key, want 1", wins)
+	}
+
+	// the key can be claimed again once the claim expires
+	expire(c, "webhook")
+	if !c.ClaimOnce("webhook", time.Minute) {
+		t.Fatal("expired claim could not be claimed again")
+	}
+}
+
+func TestSetBatch(t *testing.T) {
+	c := newTestCache(t, 10*time.Minute)
+	entries := []Entry{
+		{Key: "minute", Value: 1, TTL: time.Minute},
+		{Key: "hour", Value: 2, TTL: time.Hour},
+		{Key: "default", Value: 3},
+		{Key: "immortal", Value: 4, TTL: NoExpiration},
+	}
+	if err := c.SetBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, at := range []struct {
+		after time.Duration
+		live  []string
+	}{
+		{0, []string{"minute", "hour", "default", "immortal"}},
+		{2 * time.Minute, []string{"hour", "default", "immortal"}},
+		{20 * time.Minute, []string{"hour", "immortal"}},
+		{2 * time.Hour, []string{"immortal"}},
+	} {
+		var live []string
+		for _, e := range entries {
+			item, err := c.Get(e.Key)
+			if err != nil || item.Value != e.Value {
+				t.Fatalf("Get(%q) = %v, %v", e.Key, item, err)
+			}
+			if !item.IsExpired(now.Add(at.after)) {
+				live = append(live, e.Key)
+			}
+		}
+		if fmt.Sprint(live) != fmt.Sprint(at.live) {
+			t.Errorf("live after %v = %v, want %v", at.after, live, at.live)
+		}
+	}
+
+	// an invalid ttl stores none of the batch
+	err := c.SetBatch([]Entry{{Key: "ok", Value: 1, TTL: time.Minute}, {Key: "bad", Value: 1, TTL: -time.Second}})
+	if err == nil {
+		t.Fatal("SetBatch accepted a negative ttl")
+	}
+	if _, err := c.Get("ok"); err == nil {
+		t.Fatal("SetBatch stored part of an invalid batch")
+	}
+}
+
+func TestGetIf(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetWithTTL("fresh", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithTTL("old", 2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	backdate(c, "old", 10*time.Minute)
+	youngerThan := func(d time.Duration) func(item *Item) bool {
+		return func(item *Item) bool {
+			return time.Since(item.CreatedAt()) < d
+		}
+	}
+
+	if item, err := c.GetIf("fresh", youngerThan(5*time.Minute)); err != nil || item.Value != 1 {
+		t.Fatalf("GetIf(fresh) = %v, %v", item, err)
+	}
+	hits, misses := c.Stats().Hits, c.Stats().Misses
+	if _, err := c.GetIf("old", youngerThan(5*time.Minute)); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetIf(old) err = %v, want a miss", err)
+	}
+	if stats := c.Stats(); stats.Hits != hits || stats.Misses != misses+1 {
+		t.Fatalf("rejected item counted as hits %d -> %d, misses %d -> %d", hits, stats.Hits, misses, stats.Misses)
+	}
+	// the rejected item is still there
+	if item, err := c.Get("old"); err != nil || item.Value != 2 {
+		t.Fatalf("Get(old) after rejection = %v, %v", item, err)
+	}
+	if _, err := c.GetIf("missing", youngerThan(time.Hour)); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetIf(missing) err = %v", err)
+	}
+}
+
+func TestLoadOrStore(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var stored int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	actuals := make([]*Item, 50)
+	for i := range actuals {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			actual, loaded := c.LoadOrStore("cold", i, time.Minute)
+			if !loaded {
+				atomic.AddInt32(&stored, 1)
+			}
+			actuals[i] = actual
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	if stored != 1 {
+		t.Fatalf("%d callers stored a value, want 1", stored)
+	}
+	// every caller sees the one stored value
+	for i, actual := range actuals {
+		if actual == nil || actual.Value != actuals[0].Value {
+			t.Fatalf("caller %d got %v, caller 0 got %v", i, actual, actuals[0])
+		}
+	}
+
+	expire(c, "cold")
+	if actual, loaded := c.LoadOrStore("cold", "new", time.Minute); loaded || actual.Value != "new" {
+		t.Fatalf("LoadOrStore over an expired key = %v, %v", actual, loaded)
+	}
+}
+
+func TestLenCountsUnsweptExpired(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	for i := 0; i < 5; i++ {
+		if _, err := c.Set(fmt.Sprint(i), i, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Len() != 5 || c.ItemCount() != 5 {
+		t.Fatalf("Len = %d, ItemCount = %d, want both 5", c.Len(), c.ItemCount())
+	}
+	// the cleanup does not run again for minutes so the expired items stay in the map
+	expire(c, "0")
+	expire(c, "1")
+	if c.Len() != 5 || c.ItemCount() != 3 {
+		t.Fatalf("Len = %d, ItemCount = %d, want 5 and 3", c.Len(), c.ItemCount())
+	}
+	c.initExpiration()
+	if c.Len() != 3 || c.ItemCount() != 3 {
+		t.Fatalf("after a sweep Len = %d, ItemCount = %d, want both 3", c.Len(), c.ItemCount())
+	}
+}
+
+func TestGetExpiredOrNotFound(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Get("never"); !errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("Get(never) err = %v, want only ErrKeyNotFound", err)
+	}
+	if _, err := c.Set("lapsed", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "lapsed")
+	if _, err := c.Get("lapsed"); !errors.Is(err, ErrKeyExpired) || errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Get(lapsed) err = %v, want only ErrKeyExpired", err)
+	}
+	// the expired item is removed by the first Get, after that it is absent
+	if _, err := c.Get("lapsed"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("second Get(lapsed) err = %v, want ErrKeyNotFound", err)
+	}
+}
@@ -0,0 +1,30 @@
+package skyndiminni
+
+import "time"
+
+// WithSlowOpThreshold calls fn with the name and duration of any operation taking longer than d
+// the operations timed are "loader" runs, "bulk hydration" fetches and "cleanup" sweeps
+func WithSlowOpThreshold(d time.Duration, fn func(op string, dur time.Duration)) Option {
+	return func(c *cache) {
+		c.slowOpThreshold = d
+		c.slowOpFn = fn
+	}
+}
+
+// startOp returns the start time of a timed operation, the zero time when slow operations are not recorded
+func (c *cache) startOp() time.Time {
+	if c.slowOpFn == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// endOp calls the slow operation callback if the operation started at start took longer than the threshold
+func (c *cache) endOp(op string, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	if dur := time.Since(start); dur > c.slowOpThreshold {
+		c.safeCall("slow operation callback", func() { c.slowOpFn(op, dur) })
+	}
+}
@@ -0,0 +1,51 @@
+package skyndiminni
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlowOpThreshold(t *testing.T) {
+	var mut sync.Mutex
+	slow := map[string]time.Duration{}
+	c := newTestCache(t, NoExpiration, WithSlowOpThreshold(20*time.Millisecond, func(op string, dur time.Duration) {
+		mut.Lock()
+		slow[op] = dur
+		mut.Unlock()
+	}))
+
+	_, err := c.GetOrLoad(context.Background(), "fast", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mut.Lock()
+	n := len(slow)
+	mut.Unlock()
+	if n != 0 {
+		t.Fatalf("fast loader reported as slow: %v", slow)
+	}
+
+	_, err = c.GetOrLoad(context.Background(), "slow", time.Minute, func(ctx context.Context, key string) (interface{}, error) {
+		time.Sleep(30 * time.Millisecond)
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mut.Lock()
+	defer mut.Unlock()
+	if dur, ok := slow["loader"]; !ok || dur < 30*time.Millisecond {
+		t.Fatalf("slow ops = %v, want a loader of at least 30ms", slow)
+	}
+}
+
+func TestSlowOpOffHasNoStart(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if !c.startOp().IsZero() {
+		t.Fatal("operations are timed without a threshold")
+	}
+}
@@ -0,0 +1,162 @@
+package skyndiminni
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// WithSnapshotReads makes Keys, Range and ItemCount copy the items under the read lock and do their work after releasing it
+// writers then only wait for a plain copy instead of the whole scan
+// the tradeoff is a slice holding every stored item, 24 bytes each, allocated by every call
+func WithSnapshotReads() Option {
+	return func(c *cache) {
+		c.snapshotReads = true
+	}
+}
+
+// snapshot returns every stored item including expired ones, in insertion order with WithOrderedKeys
+func (c *cache) snapshot() []evictedItem {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	items := make([]evictedItem, 0, len(c.items))
+	if c.order != nil {
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			k := e.Value.(string)
+			items = append(items, evictedItem{k, c.items[k]})
+		}
+		return items
+	}
+	for k, item := range c.items {
+		items = append(items, evictedItem{k, item})
+	}
+	return items
+}
+
+// live filters the expired items out of a snapshot in place, keeping at most limit items unless limit is less than 0
+func live(items []evictedItem, limit int) []evictedItem {
+	now := time.Now()
+	n := 0
+	for _, e := range items {
+		if limit >= 0 && n == limit {
+			break
+		}
+		if !e.item.IsExpired(now) {
+			items[n] = e
+			n++
+		}
+	}
+	return items[:n]
+}
+
+// Snapshot is a copy of the non expired items of a cache at one point in time
+type Snapshot struct {
+	// Taken is when the snapshot was made
+	Taken time.Time
+	items map[string]*Item
+}
+
+// Snapshot copies the non expired items, later changes to the cache do not show in it
+func (c *Cache) Snapshot() *Snapshot {
+	now := time.Now()
+	items := live(c.snapshot(), -1)
+	s := &Snapshot{
+		Taken: now,
+		items: make(map[string]*Item, len(items)),
+	}
+	for _, e := range items {
+		s.items[e.key] = e.item.view()
+	}
+	return s
+}
+
+// Get returns the item for key at the time of the snapshot
+func (s *Snapshot) Get(key string) (*Item, bool) {
+	item, ok := s.items[key]
+	return item, ok
+}
+
+// Len returns the number of items in the snapshot
+func (s *Snapshot) Len() int {
+	return len(s.items)
+}
+
+// HydrateFrom stores the items of snap that have not expired in a background goroutine and closes done when it is finished
+// the items keep their expiration and creation time, keys already set in the cache are not replaced
+// Get sees each item as soon as it is stored, the keys are not sent to the broadcaster
+// hydration stops early if the cache is closed or flushed
+func (c *Cache) HydrateFrom(snap *Snapshot) (done <-chan struct{}) {
+	ch := make(chan struct{})
+	epoch := atomic.LoadInt64(&c.epoch)
+	go func() {
+		defer close(ch)
+		for k, item := range snap.items {
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+			if item.IsExpired(time.Now()) {
+				continue
+			}
+			// the item is measured again as c may have its own sizer and compression
+			next := &Item{
+				Value:        item.Value,
+				Expiration:   item.Expiration,
+				creationTime: item.creationTime,
+				onExpire:     item.onExpire,
+			}
+			if err := c.measure(next); err != nil {
+				c.reportError(err)
+				continue
+			}
+
+			c.mut.Lock()
+			if atomic.LoadInt64(&c.epoch) != epoch {
+				c.mut.Unlock()
+				return
+			}
+			now := time.Now()
+			var removed []evictedItem
+			if existing := c.items[k]; existing != nil {
+				if !existing.IsExpired(now) {
+					c.mut.Unlock()
+					continue
+				}
+				removed = append(removed, evictedItem{k, c.remove(k, EvictionExpired)})
+			}
+			evicted, err := c.store(k, next, now)
+			removed = append(removed, evicted...)
+			c.mut.Unlock()
+			c.evicted(removed)
+			if err != nil {
+				c.reportError(err)
+			}
+		}
+	}()
+	return ch
+}
+
+// DiffSnapshots returns the keys only in after, only in before and in both with values that are not reflect.DeepEqual
+// each list is sorted
+func DiffSnapshots(before, after *Snapshot) (added, removed, changed []string) {
+	for k, a := range after.items {
+		b, ok := before.items[k]
+		switch {
+		case !ok:
+			added = append(added, k)
+		case !reflect.DeepEqual(b.Value, a.Value):
+			changed = append(changed, k)
+		}
+	}
+	for k := range before.items {
+		if _, ok := after.items[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
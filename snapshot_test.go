@@ -0,0 +1,181 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeSink keeps the benchmark Range from being optimized away
+var rangeSink int
+
+// BenchmarkSetDuringRange measures Set while another goroutine keeps running Range over an ordered cache
+// by default a Set waits for the scan of the whole cache, with WithSnapshotReads only for the plain copy
+func BenchmarkSetDuringRange(b *testing.B) {
+	const items = 100000
+	for _, bb := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "locked", opts: []Option{WithOrderedKeys()}},
+		{name: "snapshot", opts: []Option{WithOrderedKeys(), WithSnapshotReads()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			c := newTestCache(b, NoExpiration, append(bb.opts, WithSetOverwrite(true))...)
+			exp := inAnHour()
+			for i := 0; i < items; i++ {
+				c.Set(fmt.Sprint("key", i), i, exp)
+			}
+
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					var x int
+					c.Range(func(key string, item *Item) bool {
+						x++
+						return true
+					})
+					rangeSink = x
+				}
+			}()
+
+			var slowest time.Duration
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				c.Set(fmt.Sprint("key", i%items), i, exp)
+				if d := time.Since(start); d > slowest {
+					slowest = d
+				}
+			}
+			b.StopTimer()
+			close(stop)
+			wg.Wait()
+			b.ReportMetric(float64(slowest.Nanoseconds()), "max-ns/set")
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	for k, v := range map[string]interface{}{
+		"same":    1,
+		"changed": 1,
+		"deleted": 1,
+		"expired": 1,
+		"equal":   []int{1, 2},
+		"touched": "x",
+	} {
+		if _, err := c.Set(k, v, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := c.Snapshot()
+
+	if _, err := c.Set("changed", 2, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "expired")
+	// a new but deeply equal value and a rewrite of the same value are not changes
+	if _, err := c.Set("equal", []int{1, 2}, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("touched", "x", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"new", "other new"} {
+		if _, err := c.Set(k, 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := c.Snapshot()
+
+	added, removed, changed := DiffSnapshots(before, after)
+	if got := fmt.Sprint(added, removed, changed); got != "[new other new] [deleted expired] [changed]" {
+		t.Fatalf("added, removed, changed = %s", got)
+	}
+	// the snapshots do not follow later changes
+	if _, err := c.Set("same", 3, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, changed := DiffSnapshots(before, after); len(changed) != 1 {
+		t.Fatalf("changed after a later write = %v", changed)
+	}
+}
+
+func TestHydrateFrom(t *testing.T) {
+	old := newTestCache(t, NoExpiration, WithCompression(1))
+	for i := 0; i < 100; i++ {
+		if _, err := old.Set(fmt.Sprint(i), fmt.Sprint("value", i), inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snap := old.Snapshot()
+	// an entry that expired after the snapshot was taken
+	snap.items["gone"] = &Item{Value: 1, Expiration: anHourAgo()}
+
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("5", "mine", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.HydrateFrom(snap):
+	case <-time.After(time.Second):
+		t.Fatal("hydration did not finish")
+	}
+
+	if n := c.Len(); n != 100 {
+		t.Fatalf("cache holds %d items after hydration, want 100", n)
+	}
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprint(i)
+		item, err := c.Get(k)
+		if err != nil {
+			t.Fatalf("Get(%q) after hydration: %v", k, err)
+		}
+		if k == "5" {
+			if item.Value != "mine" {
+				t.Fatalf("hydration replaced an existing key with %v", item.Value)
+			}
+			continue
+		}
+		orig, _ := snap.Get(k)
+		if item.Value != orig.Value || item.Expiration != orig.Expiration || item.creationTime != orig.creationTime {
+			t.Fatalf("Get(%q) = %+v, want the snapshot item %+v", k, item, orig)
+		}
+	}
+	if _, err := c.Get("gone"); err == nil {
+		t.Fatal("an expired snapshot entry was hydrated")
+	}
+}
+
+func TestHydrateFromStopsOnClose(t *testing.T) {
+	old := newTestCache(t, NoExpiration)
+	if _, err := old.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestCache(t, NoExpiration)
+	c.Close()
+	select {
+	case <-c.HydrateFrom(old.Snapshot()):
+	case <-time.After(time.Second):
+		t.Fatal("hydration of a closed cache did not finish")
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if len(c.items) != 0 {
+		t.Fatal("a closed cache was hydrated")
+	}
+}
@@ -0,0 +1,102 @@
+package skyndiminni
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the state of the cache
+type Stats struct {
+	// Hits and Misses count the lookups that found and did not find a live item
+	Hits   int64
+	Misses int64
+	// Bytes is the size of the stored values as counted against WithMaxBytes
+	// UncompressedBytes is their size before WithCompression, both need a sizer
+	Bytes             int64
+	UncompressedBytes int64
+	// StoreBreakerOpen is true while the backing store is not being called, see WithStoreBreaker
+	StoreBreakerOpen bool
+}
+
+// Stats returns a snapshot of the state of the cache
+func (c *Cache) Stats() Stats {
+	c.mut.RLock()
+	bytes, rawBytes := c.bytes, c.rawBytes
+	c.mut.RUnlock()
+	return Stats{
+		Hits:              atomic.LoadInt64(&c.hits),
+		Misses:            atomic.LoadInt64(&c.misses),
+		Bytes:             bytes,
+		UncompressedBytes: rawBytes,
+		StoreBreakerOpen:  !c.breaker.allow(time.Now()),
+	}
+}
+
+// TimeRange returns the creation times of the oldest and newest non expired items
+// ok is false if there are no non expired items
+func (c *Cache) TimeRange() (oldest, newest time.Time, ok bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	now := time.Now()
+	var min, max int64
+	for _, item := range c.items {
+		if item.IsExpired(now) {
+			continue
+		}
+		if !ok || item.creationTime < min {
+			min = item.creationTime
+		}
+		if !ok || item.creationTime > max {
+			max = item.creationTime
+		}
+		ok = true
+	}
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(min, 0), time.Unix(max, 0), true
+}
+
+// EstimatedBytes returns a rough estimate of the memory used by the stored items
+// it is the size of the values plus a fixed overhead per entry for the map slot and the Item, key contents are not counted
+// the sizes kept for WithMaxBytes or WithSizer are used if there are any, otherwise DefaultSizer is run over every live item
+func (c *Cache) EstimatedBytes() int64 {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	overhead := int64(len(c.items)) * (mapEntry + itemSize)
+	if c.sizer != nil {
+		return c.bytes + overhead
+	}
+
+	now := time.Now()
+	var n int64
+	for _, item := range c.items {
+		if item.IsExpired(now) {
+			continue
+		}
+		if sv, ok := item.Value.(storedValue); ok {
+			n += sv.storedSize() + sliceHeader
+		} else {
+			n += DefaultSizer(item.Value)
+		}
+	}
+	return n + overhead
+}
+
+// Health returns nil when the cache is open and working normally
+// otherwise an error describing the problem, for use by readiness checks
+func (c *Cache) Health() error {
+	select {
+	case <-c.done:
+		return errors.New("cache is closed")
+	default:
+	}
+	if atomic.LoadInt32(&c.cleanupRunning) == 0 {
+		return errors.New("cleanup goroutine is not running")
+	}
+	if !c.breaker.allow(time.Now()) {
+		return errors.New("store breaker is open")
+	}
+	return nil
+}
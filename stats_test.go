@@ -0,0 +1,86 @@
+package skyndiminni
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	c, err := NewCache(NoExpiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Health(); err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+	c.Close()
+	if err := c.Health(); err == nil {
+		t.Fatal("closed cache reported healthy")
+	}
+}
+
+func TestHealthBreakerOpen(t *testing.T) {
+	c := newTestCache(t, time.Hour, WithStoreBreaker(1, time.Minute), WithBulkHydration(func(keys []string) (map[string]interface{}, error) {
+		return nil, errors.New("store down")
+	}))
+	c.Get("k")
+	if err := c.Health(); err == nil {
+		t.Fatal("cache with an open breaker reported healthy")
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, _, ok := c.TimeRange(); ok {
+		t.Fatal("empty cache has a time range")
+	}
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	created := map[string]time.Time{
+		"oldest":  base,
+		"middle":  base.Add(10 * time.Minute),
+		"newest":  base.Add(20 * time.Minute),
+		"expired": base.Add(-time.Hour),
+	}
+	for k, at := range created {
+		if _, err := c.SetWithTTL(k, 1, NoExpiration); err != nil {
+			t.Fatal(err)
+		}
+		c.mut.Lock()
+		c.items[k].creationTime = at.Unix()
+		c.mut.Unlock()
+	}
+	expire(c, "expired")
+
+	oldest, newest, ok := c.TimeRange()
+	if !ok || !oldest.Equal(created["oldest"]) || !newest.Equal(created["newest"]) {
+		t.Fatalf("TimeRange = %v, %v, %v, want %v, %v", oldest, newest, ok, created["oldest"], created["newest"])
+	}
+}
+
+func TestEstimatedBytes(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"computed":   nil,
+		"maintained": {WithMaxBytes(1 << 20)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newTestCache(t, NoExpiration, opts...)
+			if n := c.EstimatedBytes(); n != 0 {
+				t.Fatalf("empty cache estimate = %d", n)
+			}
+			const values, size = 10, 1000
+			for i := 0; i < values; i++ {
+				if _, err := c.SetWithTTL(fmt.Sprint(i), strings.Repeat("x", size), time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// the payload plus at most a few hundred bytes of bookkeeping per entry
+			n := c.EstimatedBytes()
+			if n < values*size || n > values*(size+256) {
+				t.Fatalf("estimate = %d for %d values of %d bytes", n, values, size)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// SetReader reads r to the end and stores its content as a []byte under key for ttl like SetWithTTL
+// the content is buffered in memory, the reader API leaves room for backends that store it in chunks
+func (c *Cache) SetReader(key string, r io.Reader, ttl time.Duration) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = c.SetWithTTL(key, data, ttl)
+	return err
+}
+
+// GetReader returns a reader over the []byte or string stored under key
+// returns an error if the key does not exist or holds another type
+func (c *Cache) GetReader(key string) (io.ReadCloser, error) {
+	item, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := item.Value.(type) {
+	case []byte:
+		return io.NopCloser(bytes.NewReader(v)), nil
+	case string:
+		return io.NopCloser(strings.NewReader(v)), nil
+	}
+	return nil, errors.New("value is not a []byte or string")
+}
@@ -0,0 +1,71 @@
+package skyndiminni
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestSetReaderGetReader(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	data := make([]byte, 64<<10)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	// a reader returning one byte at a time makes sure the whole stream is read
+	if err := c.SetReader("blob", iotest.OneByteReader(bytes.NewReader(data)), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.GetReader("blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("read back %d bytes, err = %v, want the %d bytes written", len(got), err, len(data))
+	}
+
+	// a second reader starts from the beginning
+	r, _ = c.GetReader("blob")
+	if got, _ := io.ReadAll(r); !bytes.Equal(got, data) {
+		t.Fatal("second reader did not return the same bytes")
+	}
+}
+
+func TestSetReaderError(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	errRead := errors.New("read failed")
+	if err := c.SetReader("k", iotest.ErrReader(errRead), time.Hour); !errors.Is(err, errRead) {
+		t.Fatalf("SetReader err = %v", err)
+	}
+	if _, err := c.Get("k"); err == nil {
+		t.Fatal("a failed read stored a value")
+	}
+}
+
+func TestGetReaderTypes(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.SetWithTTL("string", "text", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetWithTTL("int", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.GetReader("string")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != "text" {
+		t.Fatalf("string reader read %q", got)
+	}
+	if _, err := c.GetReader("int"); err == nil {
+		t.Fatal("GetReader returned a reader over an int")
+	}
+	if _, err := c.GetReader("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("GetReader(missing) err = %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package skyndiminni
+
+import "context"
+
+// spanFunc starts a span for op on key, the returned function ends it with whether the operation was a hit and its error
+// it is set by WithTracer, which is only built with the otel build tag
+type spanFunc func(ctx context.Context, op, key string) (context.Context, func(hit bool, err error))
+
+// startSpan starts a span when tracing is enabled, otherwise it returns ctx and a no-op
+func (c *cache) startSpan(ctx context.Context, op, key string) (context.Context, func(hit bool, err error)) {
+	if c.spanFn == nil {
+		return ctx, func(bool, error) {}
+	}
+	return c.spanFn(ctx, op, key)
+}
@@ -0,0 +1,63 @@
+package skyndiminni
+
+import "time"
+
+// Transform calls fn for every non expired item under the write lock so the whole change is applied atomically
+// fn returns false to delete the key, a non nil item to replace its value and expiration or nil and true to keep it
+// fn gets a copy of each item and must not use the cache
+// replacements keep the creation time, onExpire callback, time bucket and pinned flag
+// no other items are evicted to make room for larger replacements
+// a replacement that cannot be stored, for example because it is over WithMaxValueSize, is reported to Errors and the item is kept
+// a recovered panic in fn keeps the item, see WithPanicRecovery
+func (c *Cache) Transform(fn func(key string, item *Item) (*Item, bool)) {
+	removed, changed := c.transform(fn)
+	c.evicted(removed)
+
+	for _, k := range changed {
+		if err := c.publish(k); err != nil {
+			c.reportError(err)
+		}
+	}
+}
+
+// transform applies fn to every non expired item under the write lock and returns the removed and changed keys
+// the lock is released even if fn panics with panic recovery off
+func (c *cache) transform(fn func(key string, item *Item) (*Item, bool)) (removed []evictedItem, changed []string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	now := time.Now()
+	for k, item := range c.items {
+		if item.IsExpired(now) {
+			continue
+		}
+		var repl *Item
+		var keep bool
+		if err := c.safeCall("transform function", func() { repl, keep = fn(k, item.clone().view()) }); err != nil {
+			continue
+		}
+		if !keep {
+			removed = append(removed, evictedItem{k, c.remove(k, EvictionDeleted)})
+			changed = append(changed, k)
+			continue
+		}
+		if repl == nil {
+			continue
+		}
+		// the replacement is a new value so it is measured again
+		next := item.clone()
+		next.Value = repl.Value
+		next.Expiration = repl.Expiration
+		next.size, next.rawSize, next.sized = 0, 0, false
+		if err := c.measure(next); err != nil {
+			c.reportError(err)
+			continue
+		}
+		if next.IsExpired(now) {
+			removed = append(removed, evictedItem{k, c.remove(k, EvictionExpired)})
+		} else {
+			c.insert(k, next)
+		}
+		changed = append(changed, k)
+	}
+	return removed, changed
+}
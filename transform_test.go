@@ -0,0 +1,165 @@
+package skyndiminni
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransform(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	exp := inAnHour()
+	for k, v := range map[string]int{"a": 1, "b": 2, "c": 3} {
+		if _, err := c.Set(k, v, exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Transform(func(key string, item *Item) (*Item, bool) {
+		switch key {
+		case "a":
+			return &Item{Value: 10, Expiration: item.Expiration}, true
+		case "b":
+			return nil, false
+		}
+		return nil, true
+	})
+	for k, want := range map[string]interface{}{"a": 10, "c": 3} {
+		if item, err := c.Get(k); err != nil || item.Value != want {
+			t.Fatalf("Get(%q) = %v, %v", k, item, err)
+		}
+	}
+	if _, err := c.Get("b"); err == nil {
+		t.Fatal("b was not deleted")
+	}
+}
+
+func TestTransformDoesNotChangeHeldItems(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	held, _ := c.Get("k")
+	c.Transform(func(key string, item *Item) (*Item, bool) {
+		item.Value = 99
+		return nil, true
+	})
+	if held.Value != 1 {
+		t.Fatalf("held value changed to %v", held.Value)
+	}
+	if item, _ := c.Get("k"); item.Value != 1 {
+		t.Fatalf("stored value changed to %v", item.Value)
+	}
+}
+
+func TestTransformKeepsPinAndBucket(t *testing.T) {
+	c := newTestCache(t, time.Hour)
+	bucket := time.Now()
+	if _, err := c.SetInBucket(bucket, "b", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetPinned("p", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	c.Transform(func(key string, item *Item) (*Item, bool) {
+		return &Item{Value: 2, Expiration: item.ExpiresAt().Add(time.Hour).Unix()}, true
+	})
+	if n := c.ExpireBucket(bucket); n != 1 {
+		t.Fatalf("ExpireBucket removed %d items, want 1", n)
+	}
+	if n := c.Evict(1); n != 0 {
+		t.Fatalf("Evict removed %d items, the pinned item should be kept", n)
+	}
+}
+
+func TestTransformDoublesTTLs(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	ttls := map[string]time.Duration{"a": 10 * time.Minute, "b": 20 * time.Minute, "tmp:a": time.Minute, "tmp:b": time.Hour}
+	for k, ttl := range ttls {
+		if _, err := c.SetWithTTL(k, k, ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.SetWithTTL("immortal", 1, NoExpiration); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Transform(func(key string, item *Item) (*Item, bool) {
+		if strings.HasPrefix(key, "tmp:") {
+			return nil, false
+		}
+		if item.Expiration <= 0 {
+			return nil, true
+		}
+		return &Item{Value: item.Value, Expiration: item.Expiration + (item.Expiration - item.creationTime)}, true
+	})
+
+	if n := c.ItemCount(); n != 3 {
+		t.Fatalf("ItemCount = %d after Transform, want 3", n)
+	}
+	for _, k := range []string{"tmp:a", "tmp:b"} {
+		if _, err := c.Get(k); err == nil {
+			t.Errorf("%s was not deleted", k)
+		}
+	}
+	for _, k := range []string{"a", "b"} {
+		item, err := c.Get(k)
+		if err != nil || item.Value != k {
+			t.Fatalf("Get(%q) = %v, %v", k, item, err)
+		}
+		// the expiration and creation time are taken a moment apart and can straddle a second
+		if got := time.Duration(item.Expiration-item.creationTime) * time.Second; got < 2*ttls[k] || got > 2*ttls[k]+2*time.Second {
+			t.Errorf("%s ttl = %v, want %v", k, got, 2*ttls[k])
+		}
+	}
+	if item, err := c.Get("immortal"); err != nil || item.Expiration > 0 {
+		t.Fatalf("Get(immortal) = %v, %v", item, err)
+	}
+}
+
+func TestTransformPanicKeepsCacheUsable(t *testing.T) {
+	for _, recovery := range []bool{true, false} {
+		t.Run(fmt.Sprintf("recovery=%v", recovery), func(t *testing.T) {
+			c := newTestCache(t, NoExpiration, WithPanicRecovery(recovery), WithSetOverwrite(true))
+			for _, k := range []string{"a", "b"} {
+				if _, err := c.Set(k, 1, inAnHour()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) == recovery {
+						t.Fatalf("panic reached the caller = %v with recovery %v", r != nil, recovery)
+					}
+				}()
+				c.Transform(func(key string, item *Item) (*Item, bool) {
+					panic("transform boom")
+				})
+			}()
+			if recovery {
+				if err := nextError(t, c); !strings.Contains(err.Error(), "transform boom") {
+					t.Fatalf("reported %v", err)
+				}
+			}
+
+			// the lock was released and the items were kept
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				if _, err := c.Set("c", 2, inAnHour()); err != nil {
+					t.Error(err)
+				}
+				for _, k := range []string{"a", "b", "c"} {
+					if _, err := c.Get(k); err != nil {
+						t.Errorf("Get(%q) after the panic: %v", k, err)
+					}
+				}
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("the cache is still locked after fn panicked")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package skyndiminni
+
+import (
+	"errors"
+	"time"
+)
+
+// Tx gives access to the cache while the write lock is held by WithLock
+// a Tx is only valid inside the function passed to WithLock
+type Tx struct {
+	c       *cache
+	removed []evictedItem
+}
+
+// WithLock runs fn while holding the write lock so every operation on tx is applied atomically
+// tx must not be used after fn returns
+func (c *Cache) WithLock(fn func(tx *Tx)) {
+	tx := &Tx{c: c.cache}
+	c.mut.Lock()
+	defer func() {
+		tx.c = nil
+		c.mut.Unlock()
+		c.evicted(tx.removed)
+	}()
+	fn(tx)
+}
+
+// Get gets a non expired value based off provided key
+func (tx *Tx) Get(key string) (*Item, error) {
+	if tx.c == nil {
+		return nil, errors.New("transaction is closed")
+	}
+	item := tx.c.items[key]
+	if item == nil {
+		return nil, ErrKeyNotFound
+	}
+	if item.IsExpired(time.Now()) {
+		tx.removed = append(tx.removed, evictedItem{key, tx.c.remove(key, EvictionExpired)})
+		return nil, ErrKeyExpired
+	}
+	return item.view(), nil
+}
+
+// Set creates or overwrites the key/value pair with expiration time
+func (tx *Tx) Set(key string, value interface{}, expirationTime int64) (*Item, error) {
+	if tx.c == nil {
+		return nil, errors.New("transaction is closed")
+	}
+	now := time.Now()
+	item := &Item{
+		Value:        value,
+		Expiration:   expirationTime,
+		creationTime: now.Unix(),
+	}
+	if err := tx.c.measure(item); err != nil {
+		return nil, err
+	}
+	removed, err := tx.c.store(key, item, now)
+	tx.removed = append(tx.removed, removed...)
+	if err != nil {
+		return nil, err
+	}
+	return item.view(), nil
+}
+
+// Delete removes the key, returns an error if the key does not exist
+// eviction callbacks run once WithLock releases the lock
+func (tx *Tx) Delete(key string) error {
+	if tx.c == nil {
+		return errors.New("transaction is closed")
+	}
+	item := tx.c.remove(key, EvictionDeleted)
+	if item == nil {
+		return ErrKeyNotFound
+	}
+	tx.removed = append(tx.removed, evictedItem{key, item})
+	return nil
+}
+
+// Keys returns all the non expired keys like Cache.Keys, nil once the transaction is closed
+func (tx *Tx) Keys() []string {
+	if tx.c == nil {
+		return nil
+	}
+	return tx.c.keys(time.Now())
+}
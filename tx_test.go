@@ -0,0 +1,97 @@
+package skyndiminni
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWithLockAtomicTransfer(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	if _, err := c.Set("a", 100, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set("b", 0, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			snap := c.Snapshot()
+			a, _ := snap.Get("a")
+			b, _ := snap.Get("b")
+			if sum := a.Value.(int) + b.Value.(int); sum != 100 {
+				t.Errorf("saw a half applied transfer, a + b = %d", sum)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		c.WithLock(func(tx *Tx) {
+			a, err := tx.Get("a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b, err := tx.Get("b")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			from, to := "a", "b"
+			if a.Value.(int) == 0 {
+				from, to = to, from
+				a, b = b, a
+			}
+			if _, err := tx.Set(from, a.Value.(int)-1, inAnHour()); err != nil {
+				t.Error(err)
+			}
+			if _, err := tx.Set(to, b.Value.(int)+1, inAnHour()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestTxInvalidAfterWithLock(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var held *Tx
+	c.WithLock(func(tx *Tx) {
+		if _, err := tx.Set("k", 1, inAnHour()); err != nil {
+			t.Fatal(err)
+		}
+		if keys := tx.Keys(); len(keys) != 1 || keys[0] != "k" {
+			t.Fatalf("Keys = %v", keys)
+		}
+		if err := tx.Delete("missing"); err == nil {
+			t.Fatal("Delete of a missing key succeeded")
+		}
+		held = tx
+	})
+	if _, err := held.Get("k"); err == nil {
+		t.Fatal("Get on a closed transaction succeeded")
+	}
+	if _, err := held.Set("k", 2, inAnHour()); err == nil {
+		t.Fatal("Set on a closed transaction succeeded")
+	}
+	if err := held.Delete("k"); err == nil {
+		t.Fatal("Delete on a closed transaction succeeded")
+	}
+	if held.Keys() != nil {
+		t.Fatal("Keys on a closed transaction returned keys")
+	}
+	if item, err := c.Get("k"); err != nil || item.Value != 1 {
+		t.Fatalf("Get = %v, %v", item, err)
+	}
+}
@@ -0,0 +1,88 @@
+package skyndiminni
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// TypedCache wraps a Cache for values of type V so callers do not have to assert them
+// values of another type stored under the same keys are treated as misses
+type TypedCache[V any] struct {
+	c *Cache
+}
+
+// NewTypedCache returns a TypedCache storing values of type V in c
+func NewTypedCache[V any](c *Cache) *TypedCache[V] {
+	return &TypedCache[V]{c: c}
+}
+
+// Get gets a non expired value of type V based off provided key, ok is false on a miss
+func (tc *TypedCache[V]) Get(key string) (value V, ok bool) {
+	item, err := tc.c.Get(key)
+	if err != nil {
+		return value, false
+	}
+	value, ok = item.Value.(V)
+	return value, ok
+}
+
+// Set stores value under key for ttl like Cache.SetWithTTL
+func (tc *TypedCache[V]) Set(key string, value V, ttl time.Duration) error {
+	_, err := tc.c.SetWithTTL(key, value, ttl)
+	return err
+}
+
+// GetMany gets the non expired values of type V for keys, keys that are missed are left out
+func (tc *TypedCache[V]) GetMany(keys []string) map[string]V {
+	found := make(map[string]V, len(keys))
+	for _, k := range keys {
+		if v, ok := tc.Get(k); ok {
+			found[k] = v
+		}
+	}
+	return found
+}
+
+// SetMany stores every value for ttl like Set
+// all values are tried, the first error is returned
+func (tc *TypedCache[V]) SetMany(values map[string]V, ttl time.Duration) error {
+	var first error
+	for k, v := range values {
+		if err := tc.Set(k, v, ttl); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// memoizers numbers the functions wrapped by Memoize so each gets its own keys
+var memoizers int64
+
+// Memoize returns a function that caches the results of fn in c for ttl keyed by the argument formatted with %#v
+// so arguments of different structure do not share a key, a cached value of another type than V is returned as an error
+// concurrent calls with the same argument share one call to fn like GetOrLoad, errors are only cached with WithErrorCaching
+// each call to Memoize uses its own keys so memoized functions sharing a cache do not collide
+func Memoize[K comparable, V any](c *Cache, ttl time.Duration, fn func(K) (V, error)) func(K) (V, error) {
+	prefix := fmt.Sprintf("memoize%d:", atomic.AddInt64(&memoizers, 1))
+	return func(arg K) (V, error) {
+		key := prefix + fmt.Sprintf("%#v", arg)
+		item, err := c.GetOrLoad(context.Background(), key, ttl, func(ctx context.Context, key string) (interface{}, error) {
+			return fn(arg)
+		})
+		var zero V
+		if err != nil {
+			return zero, err
+		}
+		// a nil result of fn is stored as a nil interface which does not assert to a pointer or interface V
+		if item.Value == nil {
+			return zero, nil
+		}
+		value, ok := item.Value.(V)
+		if !ok {
+			return zero, fmt.Errorf("memoized value for key %q is a %T", key, item.Value)
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,104 @@
+package skyndiminni
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoizeDistinctArguments(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	join := Memoize(c, time.Minute, func(arg [2]string) (string, error) {
+		return arg[0] + "|" + arg[1], nil
+	})
+	// both print as [a b c] with %v
+	for _, arg := range [][2]string{{"a b", "c"}, {"a", "b c"}} {
+		got, err := join(arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := arg[0] + "|" + arg[1]; got != want {
+			t.Fatalf("join(%q) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestMemoizeCachesResults(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	var calls int32
+	square := Memoize(c, time.Minute, func(n int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return n * n, nil
+	})
+	for i := 0; i < 3; i++ {
+		if got, err := square(4); err != nil || got != 16 {
+			t.Fatalf("square(4) = %d, %v", got, err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fn called %d times", n)
+	}
+}
+
+func TestMemoizeNilResult(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	find := Memoize(c, time.Minute, func(name string) (*int, error) { return nil, nil })
+	for i := 0; i < 2; i++ {
+		if got, err := find("x"); err != nil || got != nil {
+			t.Fatalf("find = %v, %v", got, err)
+		}
+	}
+}
+
+func TestMemoizeWrongType(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	double := Memoize(c, time.Minute, func(n int) (int, error) { return 2 * n, nil })
+	if _, err := double(1); err != nil {
+		t.Fatal(err)
+	}
+	keys := c.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "memoize") {
+		t.Fatalf("keys = %v", keys)
+	}
+	if _, err := c.Set(keys[0], "not an int", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := double(1); err == nil {
+		t.Fatalf("double(1) = %d with a string cached", got)
+	}
+}
+
+func TestTypedCacheManyStrings(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	tc := NewTypedCache[string](c)
+	if err := tc.SetMany(map[string]string{"a": "x", "b": "y"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// an int under a typed string key is a miss, not a panic
+	if _, err := c.SetWithTTL("c", 3, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	got := tc.GetMany([]string{"a", "b", "c", "missing"})
+	if len(got) != 2 || got["a"] != "x" || got["b"] != "y" {
+		t.Fatalf("GetMany = %v", got)
+	}
+}
+
+func TestTypedCacheManyInts(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	tc := NewTypedCache[int](c)
+	if err := tc.SetMany(map[string]int{"a": 1, "b": 2, "c": 3}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expire(c, "b")
+
+	got := tc.GetMany([]string{"a", "b", "c", "d"})
+	if len(got) != 2 || got["a"] != 1 || got["c"] != 3 {
+		t.Fatalf("GetMany = %v", got)
+	}
+	if got := tc.GetMany(nil); len(got) != 0 {
+		t.Fatalf("GetMany(nil) = %v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTooManyWaiters is returned instead of blocking when a key already has the number of waiters set by WithMaxWaitersPerKey
+var ErrTooManyWaiters = errors.New("too many waiters for key")
+
+// keyWaiters are the Wait calls blocked on a key, ch is closed when the key is set
+type keyWaiters struct {
+	ch chan struct{}
+	n  int
+}
+
+// WithMaxWaitersPerKey limits the number of calls blocked on a single key to n
+// this counts Wait calls for the key and GetOrLoad calls waiting on a loader already running for it
+// further calls return ErrTooManyWaiters right away, n of 0 or less is no limit
+func WithMaxWaitersPerKey(n int) Option {
+	return func(c *cache) {
+		c.maxWaiters = n
+	}
+}
+
+// Wait returns the item for key, blocking until another goroutine sets it if it does not exist
+// returns the context error if ctx is done first, see WithMaxWaitersPerKey
+func (c *Cache) Wait(ctx context.Context, key string) (*Item, error) {
+	for {
+		c.mut.Lock()
+		item := c.items[key]
+		if item != nil && !item.IsExpired(time.Now()) {
+			c.mut.Unlock()
+			return item.view(), nil
+		}
+		w := c.waiters[key]
+		if w == nil {
+			w = &keyWaiters{ch: make(chan struct{})}
+			c.waiters[key] = w
+		}
+		if c.maxWaiters > 0 && w.n >= c.maxWaiters {
+			c.mut.Unlock()
+			return nil, ErrTooManyWaiters
+		}
+		w.n++
+		c.mut.Unlock()
+
+		select {
+		case <-w.ch:
+		case <-ctx.Done():
+			c.mut.Lock()
+			w.n--
+			if w.n == 0 && c.waiters[key] == w {
+				delete(c.waiters, key)
+			}
+			c.mut.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// wake unblocks every Wait call for the key, the caller must hold the write lock
+func (c *cache) wake(key string) {
+	if w := c.waiters[key]; w != nil {
+		close(w.ch)
+		delete(c.waiters, key)
+	}
+}
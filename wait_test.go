@@ -0,0 +1,146 @@
+package skyndiminni
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitUnblocksOnSet(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	got := make(chan *Item)
+	go func() {
+		item, err := c.Wait(context.Background(), "k")
+		if err != nil {
+			t.Error(err)
+		}
+		got <- item
+	}()
+
+	// give the waiter time to block before the key is set
+	time.Sleep(10 * time.Millisecond)
+	if _, err := c.Set("k", "handoff", inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case item := <-got:
+		if item == nil || item.Value != "handoff" {
+			t.Fatalf("Wait returned %v", item)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not unblock")
+	}
+}
+
+func TestWaitLiveKeyReturnsImmediately(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	if _, err := c.Set("k", 1, inAnHour()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if item, err := c.Wait(ctx, "k"); err != nil || item.Value != 1 {
+		t.Fatalf("Wait = %v, %v", item, err)
+	}
+}
+
+func TestWaitContextDone(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Wait(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait err = %v", err)
+	}
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if len(c.waiters) != 0 {
+		t.Fatal("the timed out waiter was not unregistered")
+	}
+}
+
+func TestMaxWaitersPerKeyWait(t *testing.T) {
+	const limit = 2
+	c := newTestCache(t, NoExpiration, WithMaxWaitersPerKey(limit))
+	results := make(chan *Item, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			item, err := c.Wait(context.Background(), "hot")
+			if err != nil {
+				t.Error(err)
+			}
+			results <- item
+		}()
+	}
+	eventually(t, func() bool {
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		return c.waiters["hot"] != nil && c.waiters["hot"].n == limit
+	})
+
+	// the excess callers get the error right away while the first ones stay blocked
+	for i := 0; i < 3; i++ {
+		if _, err := c.Wait(context.Background(), "hot"); !errors.Is(err, ErrTooManyWaiters) {
+			t.Fatalf("excess Wait err = %v", err)
+		}
+	}
+	select {
+	case item := <-results:
+		t.Fatalf("a waiter returned %v before the key was set", item)
+	default:
+	}
+	// another key has its own limit
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Wait(ctx, "cold"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on another key err = %v", err)
+	}
+
+	if _, err := c.SetWithTTL("hot", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < limit; i++ {
+		if item := <-results; item == nil || item.Value != 1 {
+			t.Fatalf("blocked waiter got %v", item)
+		}
+	}
+}
+
+func TestMaxWaitersPerKeyGetOrLoad(t *testing.T) {
+	const limit = 2
+	c := newTestCache(t, NoExpiration, WithMaxWaitersPerKey(limit))
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		<-release
+		return 1, nil
+	}
+	results := make(chan error, limit+1)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "hot", time.Minute, loader)
+		results <- err
+	}()
+	<-started
+
+	for i := 0; i < limit; i++ {
+		go func() {
+			_, err := c.GetOrLoad(context.Background(), "hot", time.Minute, loader)
+			results <- err
+		}()
+	}
+	eventually(t, func() bool {
+		c.loadMut.Lock()
+		defer c.loadMut.Unlock()
+		return c.loads["hot"].waiters == limit
+	})
+	if _, err := c.GetOrLoad(context.Background(), "hot", time.Minute, loader); !errors.Is(err, ErrTooManyWaiters) {
+		t.Fatalf("excess GetOrLoad err = %v", err)
+	}
+
+	close(release)
+	for i := 0; i < limit+1; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("blocked GetOrLoad err = %v", err)
+		}
+	}
+}
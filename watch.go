@@ -0,0 +1,76 @@
+package skyndiminni
+
+import "strings"
+
+// watchBuffer is the number of events a Watch channel can hold before new ones are dropped
+const watchBuffer = 64
+
+// EventType is the kind of change an Event reports
+type EventType int
+
+const (
+	// EventSet is sent when a key is created or overwritten
+	EventSet EventType = iota
+	// EventRemove is sent when a key is deleted, expires or is evicted
+	EventRemove
+)
+
+// Event is a change to a key delivered by Watch
+type Event struct {
+	Type EventType
+	Key  string
+}
+
+// watcher is a Watch call and the pattern it filters on
+type watcher struct {
+	pattern string
+	ch      chan Event
+}
+
+// Watch returns a channel of events for keys matching pattern and a function to stop watching
+// a trailing * matches any key with the prefix before it, otherwise the key must be equal to pattern
+// events are dropped when the channel is full, the channel is closed by the stop function
+func (c *Cache) Watch(pattern string) (<-chan Event, func()) {
+	w := &watcher{
+		pattern: pattern,
+		ch:      make(chan Event, watchBuffer),
+	}
+	c.mut.Lock()
+	if c.watchers == nil {
+		c.watchers = make(map[*watcher]struct{})
+	}
+	c.watchers[w] = struct{}{}
+	c.mut.Unlock()
+
+	var stopped bool
+	return w.ch, func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		if !stopped {
+			stopped = true
+			delete(c.watchers, w)
+			close(w.ch)
+		}
+	}
+}
+
+// matches reports if key matches the pattern of the watcher
+func (w *watcher) matches(key string) bool {
+	if strings.HasSuffix(w.pattern, "*") {
+		return strings.HasPrefix(key, strings.TrimSuffix(w.pattern, "*"))
+	}
+	return key == w.pattern
+}
+
+// notify sends the event to every matching watcher without blocking, the caller must hold the write lock
+func (c *cache) notify(t EventType, key string) {
+	for w := range c.watchers {
+		if !w.matches(key) {
+			continue
+		}
+		select {
+		case w.ch <- Event{Type: t, Key: key}:
+		default:
+		}
+	}
+}
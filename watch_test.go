@@ -0,0 +1,58 @@
+package skyndiminni
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// drain returns the events already sent on ch
+func drain(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWatchPattern(t *testing.T) {
+	c := newTestCache(t, NoExpiration, WithSetOverwrite(true))
+	users, stopUsers := c.Watch("user:*")
+	defer stopUsers()
+	one, stopOne := c.Watch("user:1")
+	defer stopOne()
+
+	for _, k := range []string{"user:1", "session:1", "user:2", "user"} {
+		if _, err := c.SetWithTTL(k, 1, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Delete("session:1")
+	c.Delete("user:2")
+
+	want := []Event{{EventSet, "user:1"}, {EventSet, "user:2"}, {EventRemove, "user:2"}}
+	if got := drain(users); !reflect.DeepEqual(got, want) {
+		t.Fatalf("user:* events = %v, want %v", got, want)
+	}
+	if got := drain(one); !reflect.DeepEqual(got, []Event{{EventSet, "user:1"}}) {
+		t.Fatalf("user:1 events = %v", got)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	c := newTestCache(t, NoExpiration)
+	ch, stop := c.Watch("*")
+	stop()
+	stop()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel still open after stop")
+	}
+	// setting after stop must not send on the closed channel
+	if _, err := c.SetWithTTL("k", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+}